@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/godev/events-service/pkg/client"
+)
+
+func runStart(ctx context.Context, c *client.Client, args []string, stdout io.Writer) error {
+	eventType, err := singleArg("start", args, "type")
+	if err != nil {
+		return err
+	}
+	if err := c.Start(ctx, eventType); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "started %s\n", eventType)
+	return nil
+}
+
+func runFinish(ctx context.Context, c *client.Client, args []string, stdout io.Writer) error {
+	eventType, err := singleArg("finish", args, "type")
+	if err != nil {
+		return err
+	}
+	if err := c.Finish(ctx, eventType); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "finished %s\n", eventType)
+	return nil
+}
+
+func runList(ctx context.Context, c *client.Client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	eventType := fs.String("type", "", "only events of this type")
+	offset := fs.Int64("offset", 0, "number of events to skip")
+	limit := fs.Int64("limit", 20, "maximum number of events (1-100)")
+	all := fs.Bool("all", false, "fetch every page instead of a single one")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := client.ListOptions{Type: *eventType, Offset: *offset, Limit: *limit}
+
+	var events []client.Event
+	if *all {
+		for event, err := range c.Events(ctx, opts) {
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+	} else {
+		var err error
+		if events, err = c.List(ctx, opts); err != nil {
+			return err
+		}
+	}
+
+	if *output == outputJSON {
+		return writeJSON(stdout, events)
+	}
+	return writeEventsTable(stdout, events)
+}
+
+func runGet(ctx context.Context, c *client.Client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := singleArg("get", fs.Args(), "id")
+	if err != nil {
+		return err
+	}
+
+	event, err := c.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if *output == outputJSON {
+		return writeJSON(stdout, event)
+	}
+	return writeEventsTable(stdout, []client.Event{*event})
+}
+
+func runExport(ctx context.Context, c *client.Client, args []string, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	eventType := fs.String("type", "", "only events of this type")
+	format := fs.String("format", "ndjson", "ndjson or csv")
+	out := fs.String("out", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "ndjson" && *format != "csv" {
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+
+	w := stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+
+	var write func(event client.Event) error
+	var flush func() error
+	switch *format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"id", "tenant", "type", "state", "started_at", "finished_at", "version"}); err != nil {
+			return err
+		}
+		write = func(event client.Event) error {
+			var finishedAt string
+			if event.FinishedAt != nil {
+				finishedAt = event.FinishedAt.UTC().Format(time.RFC3339Nano)
+			}
+			return cw.Write([]string{
+				event.ID.Hex(),
+				event.Tenant,
+				event.Type,
+				stateName(event.State),
+				event.StartedAt.UTC().Format(time.RFC3339Nano),
+				finishedAt,
+				strconv.FormatInt(event.Version, 10),
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		enc := json.NewEncoder(w)
+		write = func(event client.Event) error {
+			return enc.Encode(event)
+		}
+		flush = func() error { return nil }
+	}
+
+	for event, err := range c.Events(ctx, client.ListOptions{Type: *eventType}) {
+		if err != nil {
+			return err
+		}
+		if err := write(event); err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+func runStats(ctx context.Context, c *client.Client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	eventType := fs.String("type", "", "only this type")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	stats, err := c.Stats(ctx, *eventType)
+	if err != nil {
+		return err
+	}
+
+	if *output == outputJSON {
+		return writeJSON(stdout, stats)
+	}
+	return writeStatsTable(stdout, stats)
+}
+
+func runWatch(ctx context.Context, c *client.Client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	eventType := fs.String("type", "", "only events of this type")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(stdout)
+	if *output == outputTable {
+		fmt.Fprintln(stdout, "TIME\tID\tTYPE\tSTATE\tVERSION")
+	}
+
+	err := c.Watch(ctx, *eventType, func(event client.Event) error {
+		if *output == outputJSON {
+			return enc.Encode(event)
+		}
+		_, err := fmt.Fprintf(stdout, "%s\t%s\t%s\t%s\t%d\n",
+			time.Now().Format(time.RFC3339), event.ID.Hex(), event.Type, stateName(event.State), event.Version)
+		return err
+	})
+	// Ctrl-C is the normal way to stop tailing.
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func singleArg(cmd string, args []string, name string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: eventsctl %s <%s>", cmd, name)
+	}
+	return args[0], nil
+}
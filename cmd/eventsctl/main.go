@@ -0,0 +1,110 @@
+// Command eventsctl operates the events service through its REST API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/godev/events-service/pkg/client"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, c *client.Client, args []string, stdout io.Writer) error
+	// stream commands run until interrupted and ignore -timeout.
+	stream bool
+}
+
+var commands = map[string]command{
+	"start":  {usage: "start <type>", run: runStart},
+	"finish": {usage: "finish <type>", run: runFinish},
+	"list":   {usage: "list [-type T] [-offset N] [-limit N] [-all] [-o table|json]", run: runList},
+	"get":    {usage: "get [-o table|json] <id>", run: runGet},
+	"export": {usage: "export [-type T] [-format ndjson|csv] [-out FILE]", run: runExport, stream: true},
+	"stats":  {usage: "stats [-type T] [-o table|json]", run: runStats},
+	"watch":  {usage: "watch [-type T] [-o table|json]", run: runWatch, stream: true},
+}
+
+var commandOrder = []string{"start", "finish", "list", "get", "export", "stats", "watch"}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "eventsctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("eventsctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", envOr("EVENTS_ADDR", "http://localhost:8080"), "events service base URL (env EVENTS_ADDR)")
+	apiKey := fs.String("api-key", os.Getenv("EVENTS_API_KEY"), "API key (env EVENTS_API_KEY)")
+	token := fs.String("token", os.Getenv("EVENTS_TOKEN"), "JWT bearer token (env EVENTS_TOKEN)")
+	tenant := fs.String("tenant", os.Getenv("EVENTS_TENANT"), "tenant to operate in (env EVENTS_TENANT)")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout for non-streaming commands")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: eventsctl [-addr URL] [-api-key KEY | -token JWT] [-tenant T] [-timeout D] <command> [flags]")
+		fmt.Fprintln(stderr, "\nCommands:")
+		for _, name := range commandOrder {
+			fmt.Fprintln(stderr, "  "+commands[name].usage)
+		}
+		fmt.Fprintln(stderr, "\nGlobal flags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	var opts []client.Option
+	if *apiKey != "" {
+		opts = append(opts, client.WithAPIKey(*apiKey))
+	}
+	if *token != "" {
+		opts = append(opts, client.WithBearerToken(*token))
+	}
+	if *tenant != "" {
+		opts = append(opts, client.WithTenant(*tenant))
+	}
+
+	c, err := client.New(*addr, opts...)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if !cmd.stream {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	return cmd.run(ctx, c, fs.Args()[1:], stdout)
+}
+
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
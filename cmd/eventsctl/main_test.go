@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/pkg/client"
+)
+
+// recordingServer answers every request with body and remembers the last
+// request it got.
+type recordingServer struct {
+	*httptest.Server
+	last   *http.Request
+	status int
+	body   string
+}
+
+func newRecordingServer(t *testing.T, body string) *recordingServer {
+	s := &recordingServer{status: http.StatusOK, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.last = r
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.status)
+		fmt.Fprint(w, s.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func runCtl(t *testing.T, srv *recordingServer, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(append([]string{"-addr", srv.URL}, args...), &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+func TestRunUsage(t *testing.T) {
+	srv := newRecordingServer(t, "")
+
+	_, stderr, err := runCtl(t, srv)
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	for _, name := range commandOrder {
+		assert.Contains(t, stderr, commands[name].usage)
+	}
+
+	_, _, err = runCtl(t, srv, "restart")
+	assert.EqualError(t, err, `unknown command "restart"`)
+
+	_, _, err = runCtl(t, srv, "start")
+	assert.EqualError(t, err, "usage: eventsctl start <type>")
+
+	_, _, err = runCtl(t, srv, "list", "-o", "yaml")
+	assert.ErrorContains(t, err, `must be "table" or "json"`)
+
+	_, _, err = runCtl(t, srv, "export", "-format", "xml")
+	assert.EqualError(t, err, `unknown export format "xml"`)
+
+	assert.Nil(t, srv.last, "invalid commands send nothing")
+}
+
+func TestRunStart(t *testing.T) {
+	srv := newRecordingServer(t, "")
+
+	stdout, _, err := runCtl(t, srv, "-api-key", "secret", "-tenant", "acme", "start", "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "started deploy\n", stdout)
+
+	require.NotNil(t, srv.last)
+	assert.Equal(t, http.MethodPost, srv.last.Method)
+	assert.Equal(t, "/v1/start", srv.last.URL.Path)
+	assert.Equal(t, "secret", srv.last.Header.Get("X-API-Key"))
+	assert.Equal(t, "acme", srv.last.Header.Get("X-Tenant-ID"))
+}
+
+func TestRunList(t *testing.T) {
+	id := "65f1c0ffee00000000000001"
+	srv := newRecordingServer(t, `{"events": [{"id": "`+id+`", "type": "deploy", "state": 0, "startedAt": "2024-03-13T10:00:00Z", "version": 1}]}`)
+
+	stdout, _, err := runCtl(t, srv, "list", "-type", "deploy", "-offset", "10", "-limit", "5", "-o", "json")
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{"type": {"deploy"}, "offset": {"10"}, "limit": {"5"}}, srv.last.URL.Query())
+
+	var events []client.Event
+	require.NoError(t, json.Unmarshal([]byte(stdout), &events))
+	require.Len(t, events, 1)
+	assert.Equal(t, id, events[0].ID.Hex())
+
+	stdout, _, err = runCtl(t, srv, "list")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "ID"))
+	assert.Contains(t, lines[1], "deploy")
+	assert.Contains(t, lines[1], "started")
+}
+
+func TestRunExportCSV(t *testing.T) {
+	id := "65f1c0ffee00000000000001"
+	srv := newRecordingServer(t, `{"events": [{"id": "`+id+`", "tenant": "acme", "type": "deploy", "state": 1, "startedAt": "2024-03-13T10:00:00Z", "finishedAt": "2024-03-13T10:05:00Z", "version": 2}]}`)
+
+	stdout, _, err := runCtl(t, srv, "-tenant", "acme", "export", "-format", "csv")
+	require.NoError(t, err)
+	assert.Equal(t, "id,tenant,type,state,started_at,finished_at,version\n"+
+		id+",acme,deploy,finished,2024-03-13T10:00:00Z,2024-03-13T10:05:00Z,2\n", stdout)
+}
+
+func TestRunStats(t *testing.T) {
+	srv := newRecordingServer(t, `{"stats": [{"type": "deploy", "running": 1, "finished": 3, "avgDurationMs": 1500, "lastStartedAt": "2024-03-13T10:00:00Z"}]}`)
+
+	stdout, _, err := runCtl(t, srv, "stats", "-type", "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/stats", srv.last.URL.Path)
+	assert.Equal(t, "deploy", srv.last.URL.Query().Get("type"))
+	assert.Contains(t, stdout, "1.5s")
+}
+
+func TestRunReportsAPIErrors(t *testing.T) {
+	srv := newRecordingServer(t, `{"message": "no unfinished event found"}`)
+	srv.status = http.StatusNotFound
+
+	_, _, err := runCtl(t, srv, "-timeout", time.Second.String(), "finish", "deploy")
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "no unfinished event found", apiErr.Message)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/godev/events-service/pkg/client"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type outputValue string
+
+func (o *outputValue) String() string {
+	return string(*o)
+}
+
+func (o *outputValue) Set(v string) error {
+	if v != outputTable && v != outputJSON {
+		return fmt.Errorf("must be %q or %q", outputTable, outputJSON)
+	}
+	*o = outputValue(v)
+	return nil
+}
+
+func outputFlag(fs *flag.FlagSet) *outputValue {
+	o := outputValue(outputTable)
+	fs.Var(&o, "o", "output format: table or json")
+	return &o
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeEventsTable(w io.Writer, events []client.Event) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tSTATE\tSTARTED\tFINISHED\tVERSION")
+	for _, event := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n",
+			event.ID.Hex(),
+			event.Type,
+			stateName(event.State),
+			event.StartedAt.Local().Format(time.DateTime),
+			formatOptionalTime(event.FinishedAt, time.DateTime),
+			event.Version)
+	}
+	return tw.Flush()
+}
+
+func writeStatsTable(w io.Writer, stats []client.EventStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tRUNNING\tFINISHED\tAVG DURATION\tLAST STARTED")
+	for _, s := range stats {
+		avg := time.Duration(s.AvgDurationMs * float64(time.Millisecond)).Round(time.Millisecond)
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n",
+			s.Type, s.Running, s.Finished, avg, s.LastStartedAt.Local().Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+func stateName(state client.EventState) string {
+	switch state {
+	case client.EventStateStarted:
+		return "started"
+	case client.EventStateFinished:
+		return "finished"
+	default:
+		return fmt.Sprintf("unknown(%d)", state)
+	}
+}
+
+func formatOptionalTime(t *time.Time, layout string) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(layout)
+}
@@ -1,46 +1,425 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	stdlog "log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
+	"github.com/godev/events-service/internal/auth"
 	"github.com/godev/events-service/internal/config"
-	"github.com/godev/events-service/internal/db"
 	"github.com/godev/events-service/internal/handler"
+	grpchandler "github.com/godev/events-service/internal/handler/grpc"
+	"github.com/godev/events-service/internal/health"
 	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/metrics"
+	"github.com/godev/events-service/internal/middleware"
+	"github.com/godev/events-service/internal/ratelimit"
 	mongorepo "github.com/godev/events-service/internal/repository/mongo"
+	"github.com/godev/events-service/internal/retention"
 	"github.com/godev/events-service/internal/service"
+	"github.com/godev/events-service/internal/tlsconfig"
+	"github.com/godev/events-service/internal/tracing"
 )
 
 func main() {
-	logger.Init()
+	cfg, cmdLine, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		stdlog.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if cmdLine.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			stdlog.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	var command string
+	var dryRun bool
+	if len(cmdLine.Args) > 0 {
+		command = cmdLine.Args[0]
+		switch {
+		case command == rebuildCommand && len(cmdLine.Args) == 1:
+		case command == migrateCommand:
+			if dryRun, err = parseMigrateArgs(cmdLine.Args[1:]); err != nil {
+				stdlog.Fatalf("Invalid %s command: %v", migrateCommand, err)
+			}
+		default:
+			stdlog.Fatalf("Unknown command %q", strings.Join(cmdLine.Args, " "))
+		}
+	}
+
+	if err := logger.Init(&cfg.Log); err != nil {
+		stdlog.Fatalf("Failed to initialize logger: %v", err)
+	}
 	defer logger.Sync()
 	log := logger.Get()
 
-	cfg := config.New()
-
-	mongodb, err := db.NewMongoDB(log, &cfg.Mongo)
+	shutdownTracing, err := tracing.Init(context.Background(), &cfg.Tracing)
 	if err != nil {
-		log.Fatal("Failed to initialize MongoDB", zap.Error(err))
+		log.Fatal("Failed to initialize tracing", zap.Error(err))
 	}
 	defer func() {
-		if err := mongodb.Close(); err != nil {
-			log.Error("Failed to close MongoDB connection", zap.Error(err))
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error("Failed to flush traces", zap.Error(err))
 		}
 	}()
 
-	eventRepo := mongorepo.NewEventRepository(mongodb.GetDatabase())
+	appMetrics := metrics.New()
+	checker := health.NewChecker(2 * time.Second)
+
+	store, err := openStorage(cfg, appMetrics, checker, log)
+	if err != nil {
+		log.Fatal("Failed to initialize storage", zap.Error(err))
+	}
+
+	if command == rebuildCommand {
+		if store.mongo == nil {
+			log.Fatal("Rebuilding projections needs the mongo storage backend")
+		}
+		if err := rebuildProjections(store.mongo, log); err != nil {
+			log.Fatal("Failed to rebuild event projections", zap.Error(err))
+		}
+		runPhase(log, shutdownPhase{name: "close storage", timeout: cfg.Server.CloseTimeout, run: store.close})
+		return
+	}
+
+	if command == migrateCommand {
+		if store.mongo == nil {
+			log.Fatal("Migrating the schema needs the mongo storage backend")
+		}
+		if err := migrateSchema(store.mongo, dryRun, log); err != nil {
+			log.Fatal("Failed to migrate the schema", zap.Error(err))
+		}
+		runPhase(log, shutdownPhase{name: "close storage", timeout: cfg.Server.CloseTimeout, run: store.close})
+		return
+	}
+
+	eventRepo := appMetrics.InstrumentRepository(store.events)
 	eventService := service.NewEventService(eventRepo)
 	eventHandler := handler.NewEventHandler(eventService)
+	auditHandler := handler.NewAuditHandler(service.NewAuditService(store.audit))
 
-	router := gin.Default()
+	retentionJob, err := newRetentionJob(&cfg.Retention, store, log)
+	if err != nil {
+		log.Fatal("Failed to initialize retention", zap.Error(err))
+	}
+	retentionHandler := handler.NewRetentionHandler(retentionJob)
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	retentionDone := make(chan struct{})
+	go func() {
+		defer close(retentionDone)
+		retentionJob.Run(retentionCtx, cfg.Retention.Interval)
+	}()
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("Failed to set the trusted proxies", zap.Error(err))
+	}
+	router.Use(
+		middleware.RequestID(log),
+		middleware.Logger(log, "/healthz", "/readyz"),
+		middleware.Recovery(log),
+	)
+	// Probes are registered ahead of the tracing and metrics middleware so
+	// that they do not flood traces and request metrics.
+	router.GET("/healthz", checker.Live)
+	router.GET("/readyz", checker.Ready)
+	router.Use(otelgin.Middleware(cfg.Tracing.ServiceName))
+	router.Use(middleware.Metrics(appMetrics))
+
+	authenticator, err := newAuthenticator(&cfg.Auth, cfg.Server.TLS.ClientCAFile != "", store)
+	if err != nil {
+		log.Fatal("Failed to initialize authentication", zap.Error(err))
+	}
 
 	v1 := router.Group("/v1")
+	v1.Use(
+		middleware.MaxBodySize(int64(cfg.Server.MaxBodyBytes)),
+		middleware.Authenticate(authenticator),
+		middleware.Tenant(),
+	)
+	limits, err := rateLimitPolicy(&cfg.RateLimit)
+	if err != nil {
+		log.Fatal("Failed to initialize rate limiting", zap.Error(err))
+	}
+	limitPolicy := ratelimit.NewCurrentPolicy(limits)
+	var limiter ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		limiter, err = newLimiter(&cfg.RateLimit, store)
+		if err != nil {
+			log.Fatal("Failed to initialize rate limiting", zap.Error(err))
+		}
+		v1.Use(middleware.RateLimit(limiter, limitPolicy))
+	}
+
+	// Every feature has added its indexes by now. Until the schema is
+	// migrated and they are built, writes fail with 503 and the service
+	// reports not ready.
+	indexCtx, stopIndexes := context.WithCancel(context.Background())
+	indexesDone := make(chan struct{})
+	go func() {
+		defer close(indexesDone)
+		if store.indexes == nil {
+			return
+		}
+		if cfg.Mongo.MigrateOnStartup {
+			if err := migrateOnStartup(indexCtx, &cfg.Mongo, store.mongo, log); err != nil {
+				return
+			}
+		}
+		store.indexes.Run(indexCtx)
+	}()
+
+	// Cancelled once the server shuts down, so that watch streams do not
+	// hold up draining.
+	streams, stopStreams := context.WithCancel(context.Background())
+
+	read := v1.Group("", middleware.RequireScope(auth.ScopeEventsRead))
 	{
-		v1.GET("", eventHandler.ListEvents)
-		v1.POST("/start", eventHandler.StartEvent)
-		v1.POST("/finish", eventHandler.FinishEvent)
+		read.GET("", eventHandler.ListEvents)
+		read.GET("/events/:id", eventHandler.GetEvent)
+		read.GET("/events/:id/history", eventHandler.GetEventHistory)
+		read.GET("/stats", eventHandler.GetStats)
+		read.GET("/watch", middleware.CancelWith(streams), eventHandler.WatchEvents)
 	}
 
-	RunServer(router, cfg.Server.Port, log)
+	write := v1.Group("", middleware.RequireScope(auth.ScopeEventsWrite))
+	write.Use(middleware.Idempotency(middleware.NewIdempotencyStore(cfg.Server.IdempotencyTTL, cfg.Server.IdempotencyMaxKeys)))
+	{
+		write.POST("/start", eventHandler.StartEvent)
+		write.POST("/finish", eventHandler.FinishEvent)
+		write.POST("/batch", eventHandler.BatchEvents)
+	}
+
+	v1.GET("/audit", middleware.RequireScope(auth.ScopeAuditRead), auditHandler.ListAudit)
+
+	// Without authentication these need auth.anonymous_scopes to grant
+	// audit:read or admin.
+	adminAPI := v1.Group("/admin", middleware.RequireScope(auth.ScopeAdmin))
+	adminAPI.Any("/log/level", gin.WrapH(logger.Level()))
+	adminAPI.GET("/retention/preview", retentionHandler.Preview)
+
+	// The gRPC server shares the certificates of the HTTP server, so that
+	// credentials never travel in plain text on one of them.
+	var tlsConfig *tls.Config
+	if cfg.Server.TLS.CertFile != "" {
+		tlsConfig, err = tlsconfig.New(&cfg.Server.TLS, log)
+		if err != nil {
+			log.Fatal("Failed to load TLS certificates", zap.Error(err))
+		}
+	}
+
+	unary := []grpc.UnaryServerInterceptor{grpchandler.AuthUnaryInterceptor(authenticator)}
+	if limiter != nil {
+		unary = append(unary, grpchandler.RateLimitUnaryInterceptor(limiter, limitPolicy))
+	}
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(
+			grpchandler.AuthStreamInterceptor(authenticator),
+			grpchandler.CancelStreamsWith(streams),
+		),
+	}
+	if tlsConfig != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
+	grpchandler.NewEventServer(eventService).Register(grpcServer)
+
+	admin := http.NewServeMux()
+	admin.Handle("/metrics", appMetrics.Handler())
+	if store.indexes != nil {
+		admin.HandleFunc("/storage/indexes", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(store.indexes.Status())
+		})
+	}
+
+	reload := &reloader{
+		args:      os.Args[1:],
+		current:   cfg,
+		rateLimit: limitPolicy,
+		retention: retentionJob,
+		log:       log,
+	}
+
+	// Runs once no more requests are served, in this order.
+	shutdown := []shutdownPhase{
+		{name: "stop background workers", timeout: cfg.Server.CloseTimeout, run: func(ctx context.Context) error {
+			stopRetention()
+			stopIndexes()
+			for _, done := range []chan struct{}{retentionDone, indexesDone} {
+				select {
+				case <-done:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		}},
+		{name: "close storage", timeout: cfg.Server.CloseTimeout, run: store.close},
+	}
+
+	RunServer(router, grpcServer, admin, checker, reload.Reload, stopStreams, shutdown, tlsConfig, &cfg.Server, log)
+}
+
+// newAuthenticator builds the authenticator from the configured key sources
+// and JWKS, and from client certificates when mutual TLS is on; with
+// authentication disabled every caller without a client certificate is
+// anonymous, with the configured scopes.
+func newAuthenticator(cfg *config.AuthConfig, clientCerts bool, store *storage) (*auth.Authenticator, error) {
+	if !cfg.Enabled {
+		authenticator := auth.NewAnonymousAuthenticator(cfg.AnonymousScopes)
+		if clientCerts {
+			authenticator.AcceptClientCerts(cfg.ClientCertScopes)
+		}
+		return authenticator, nil
+	}
+
+	var stores []auth.KeyStore
+	if len(cfg.APIKeys) > 0 {
+		keys := make([]auth.APIKey, 0, len(cfg.APIKeys))
+		for _, key := range cfg.APIKeys {
+			keys = append(keys, auth.APIKey{Name: key.Name, Hash: key.Hash, Scopes: key.Scopes, Tenant: key.Tenant})
+		}
+		stores = append(stores, auth.NewStaticKeyStore(keys))
+	}
+	if cfg.APIKeysCollection != "" {
+		if store.mongo == nil {
+			return nil, errors.New("API keys in a collection need the mongo storage backend")
+		}
+		store.indexes.Add(mongorepo.APIKeyIndexes(cfg.APIKeysCollection)...)
+		stores = append(stores, mongorepo.NewAPIKeyStore(store.mongo, cfg.APIKeysCollection))
+	}
+
+	var verifier *auth.JWTVerifier
+	if cfg.JWKSFile != "" {
+		var err error
+		verifier, err = auth.NewJWTVerifier(cfg.JWKSFile, cfg.JWTIssuer, cfg.JWTAudience)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(stores) == 0 && verifier == nil && !clientCerts {
+		return nil, errors.New("authentication is enabled but no API keys, JWKS file or client CA are configured")
+	}
+
+	var keys auth.KeyStore
+	if len(stores) > 0 {
+		keys = auth.MultiKeyStore(stores...)
+	}
+	authenticator := auth.NewAuthenticator(keys, verifier)
+	if clientCerts {
+		authenticator.AcceptClientCerts(cfg.ClientCertScopes)
+	}
+	return authenticator, nil
+}
+
+// newLimiter builds the limiter of the configured backend, which the REST
+// and gRPC APIs share.
+func newLimiter(cfg *config.RateLimitConfig, store *storage) (ratelimit.Limiter, error) {
+	switch cfg.Backend {
+	case "memory":
+		return ratelimit.NewMemoryLimiter(), nil
+	case "mongo":
+		if store.mongo == nil {
+			return nil, errors.New("the mongo rate limit backend needs the mongo storage backend")
+		}
+		store.indexes.Add(mongorepo.RateLimitIndexes(cfg.Collection)...)
+		return mongorepo.NewRateLimiter(store.mongo, cfg.Collection), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+	}
+}
+
+// rateLimitPolicy parses the configured limits.
+func rateLimitPolicy(cfg *config.RateLimitConfig) (ratelimit.Policy, error) {
+	policy := ratelimit.Policy{Routes: make(map[string]ratelimit.Limit, len(cfg.Routes))}
+	if cfg.Default != "" {
+		limit, err := ratelimit.ParseLimit(cfg.Default)
+		if err != nil {
+			return ratelimit.Policy{}, err
+		}
+		policy.Default = limit
+	}
+	for route, val := range cfg.Routes {
+		limit, err := ratelimit.ParseLimit(val)
+		if err != nil {
+			return ratelimit.Policy{}, fmt.Errorf("route %s: %w", route, err)
+		}
+		policy.Routes[route] = limit
+	}
+	return policy, nil
+}
+
+// newRetentionJob builds the job that purges expired events from the
+// configured retentions and archive.
+func newRetentionJob(cfg *config.RetentionConfig, store *storage, log *zap.Logger) (*retention.Job, error) {
+	policy, err := retentionPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var archiver retention.Archiver
+	switch cfg.Archive {
+	case "none":
+	case "collection":
+		archiver = store.archive
+	case "file":
+		if archiver, err = retention.NewFileArchiver(cfg.ArchiveDir); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown retention archive %q", cfg.Archive)
+	}
+
+	return retention.NewJob(store.retention, archiver, store.retentionLock, policy, int64(cfg.BatchSize), log), nil
+}
+
+// instanceName names this process in the locks it shares with other
+// instances.
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}
+
+// retentionPolicy parses the configured retentions.
+func retentionPolicy(cfg *config.RetentionConfig) (retention.Policy, error) {
+	policy := retention.Policy{Types: make(map[string]time.Duration, len(cfg.Types))}
+	if cfg.Default != "" {
+		d, err := retention.ParseDuration(cfg.Default)
+		if err != nil {
+			return retention.Policy{}, err
+		}
+		policy.Default = d
+	}
+	for eventType, val := range cfg.Types {
+		d, err := retention.ParseDuration(val)
+		if err != nil {
+			return retention.Policy{}, fmt.Errorf("type %s: %w", eventType, err)
+		}
+		policy.Types[eventType] = d
+	}
+	return policy, nil
 }
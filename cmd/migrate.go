@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/backoff"
+	"github.com/godev/events-service/internal/config"
+	mongorepo "github.com/godev/events-service/internal/repository/mongo"
+)
+
+// migrateCommand applies the pending schema migrations instead of starting
+// the server: "events-service migrate [--dry-run]".
+const migrateCommand = "migrate"
+
+// parseMigrateArgs parses the arguments following migrateCommand.
+func parseMigrateArgs(args []string) (dryRun bool, err error) {
+	flags := flag.NewFlagSet(migrateCommand, flag.ContinueOnError)
+	flags.BoolVar(&dryRun, "dry-run", false, "only report the pending migrations and the documents they would change")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+	if flags.NArg() > 0 {
+		return false, fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+	return dryRun, nil
+}
+
+func migrateSchema(database *mongo.Database, dryRun bool, log *zap.Logger) error {
+	start := time.Now()
+	results, err := mongorepo.Migrate(context.Background(), database, instanceName(), dryRun)
+	logMigrations(log, results, dryRun)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Schema is up to date",
+		zap.Bool("dry_run", dryRun),
+		zap.Int("migrations", len(results)),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// migrateOnStartup applies the pending migrations before the indexes are
+// built, since migrations may fix the documents the indexes cover. Like
+// the indexes it retries until MongoDB is reachable and no other instance
+// holds the lock, and it gives up only when ctx is done.
+func migrateOnStartup(ctx context.Context, cfg *config.MongoConfig, database *mongo.Database, log *zap.Logger) error {
+	retry := backoff.Backoff{Initial: cfg.RetryInitialInterval, Max: cfg.RetryMaxInterval}
+	owner := instanceName()
+	return backoff.Retry(ctx, retry, func(ctx context.Context) error {
+		results, err := mongorepo.Migrate(ctx, database, owner, false)
+		logMigrations(log, results, false)
+		return err
+	}, func(attempt int, err error, wait time.Duration) {
+		log.Warn("Failed to migrate the schema",
+			zap.Error(err),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", wait))
+	})
+}
+
+func logMigrations(log *zap.Logger, results []mongorepo.MigrationResult, dryRun bool) {
+	message := "Applied schema migration"
+	if dryRun {
+		message = "Pending schema migration"
+	}
+	for _, r := range results {
+		log.Info(message,
+			zap.Int("version", r.Version),
+			zap.String("name", r.Name),
+			zap.Int64("documents", r.Documents))
+	}
+}
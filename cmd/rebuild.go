@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	mongorepo "github.com/godev/events-service/internal/repository/mongo"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// rebuildCommand regenerates the events collection from the event log
+// instead of starting the server: "events-service rebuild".
+const rebuildCommand = "rebuild"
+
+func rebuildProjections(database *mongo.Database, log *zap.Logger) error {
+	ctx := tenant.WithAllTenants(context.Background())
+	start := time.Now()
+
+	indexes := append(mongorepo.EventIndexes(), mongorepo.EventLogIndexes()...)
+	if err := mongorepo.EnsureIndexes(ctx, database, indexes...); err != nil {
+		return err
+	}
+
+	repo := mongorepo.NewEventSourcedRepository(database, nil)
+	rebuilt, reimported, err := repo.Rebuild(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Rebuilt event projections",
+		zap.Int("events", rebuilt),
+		zap.Int("reimported", reimported),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
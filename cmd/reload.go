@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/godev/events-service/internal/config"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/ratelimit"
+	"github.com/godev/events-service/internal/retention"
+)
+
+// errRestartRequired rejects a reload that changes settings which only take
+// effect on startup.
+var errRestartRequired = errors.New("configuration changes need a restart")
+
+// reloader loads the configuration again on SIGHUP and applies the
+// settings that may change while the service runs: the log level, rate
+// limits and retentions. A reload either applies all of its changes or
+// none.
+type reloader struct {
+	args      []string
+	current   *config.Config
+	rateLimit *ratelimit.CurrentPolicy
+	retention *retention.Job
+	log       *zap.Logger
+}
+
+func (r *reloader) Reload() error {
+	cfg, _, err := config.Load(r.args)
+	if err != nil {
+		return err
+	}
+
+	changes := config.Diff(r.current, cfg)
+	if len(changes) == 0 {
+		r.log.Info("Configuration reloaded without changes")
+		return nil
+	}
+
+	var restart int
+	for _, change := range changes {
+		if !change.Reload {
+			restart++
+		}
+	}
+	if restart > 0 {
+		for _, change := range changes {
+			r.log.Warn("Configuration change rejected",
+				zap.String("setting", change.Path),
+				zap.String("old", change.Old),
+				zap.String("new", change.New),
+				zap.Bool("needs_restart", !change.Reload))
+		}
+		return fmt.Errorf("%w: %d of %d changed settings", errRestartRequired, restart, len(changes))
+	}
+
+	// Everything is parsed before anything is applied.
+	level, err := zapcore.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return err
+	}
+	limits, err := rateLimitPolicy(&cfg.RateLimit)
+	if err != nil {
+		return err
+	}
+	retentions, err := retentionPolicy(&cfg.Retention)
+	if err != nil {
+		return err
+	}
+
+	// The level is only set when it changed, so that a level set through
+	// the admin endpoint survives unrelated reloads.
+	if cfg.Log.Level != r.current.Log.Level {
+		logger.Level().SetLevel(level)
+	}
+	r.rateLimit.Set(limits)
+	r.retention.SetPolicy(retentions)
+	r.current = cfg
+
+	for _, change := range changes {
+		r.log.Info("Configuration change applied",
+			zap.String("setting", change.Path),
+			zap.String("old", change.Old),
+			zap.String("new", change.New))
+	}
+	return nil
+}
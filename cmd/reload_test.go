@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/godev/events-service/internal/config"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/ratelimit"
+	"github.com/godev/events-service/internal/retention"
+)
+
+// newReloader starts from the configuration file content and returns the
+// reloader with a function that rewrites the file.
+func newReloader(t *testing.T, content string) (*reloader, func(content string)) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	write(content)
+
+	args := []string{"--config", path}
+	cfg, _, err := config.Load(args)
+	require.NoError(t, err)
+	limits, err := rateLimitPolicy(&cfg.RateLimit)
+	require.NoError(t, err)
+	retentions, err := retentionPolicy(&cfg.Retention)
+	require.NoError(t, err)
+
+	previous := logger.Level().Level()
+	t.Cleanup(func() { logger.Level().SetLevel(previous) })
+	logger.Level().SetLevel(zapcore.InfoLevel)
+
+	return &reloader{
+		args:      args,
+		current:   cfg,
+		rateLimit: ratelimit.NewCurrentPolicy(limits),
+		retention: retention.NewJob(nil, nil, nil, retentions, 100, zap.NewNop()),
+		log:       zap.NewNop(),
+	}, write
+}
+
+func TestReloadAppliesLogLevel(t *testing.T) {
+	r, write := newReloader(t, "log:\n  level: info\n")
+
+	write("log:\n  level: debug\n")
+	require.NoError(t, r.Reload())
+
+	assert.Equal(t, zapcore.DebugLevel, logger.Level().Level())
+	assert.Equal(t, "debug", r.current.Log.Level)
+}
+
+func TestReloadRejectsRestartChanges(t *testing.T) {
+	r, write := newReloader(t, "log:\n  level: info\n")
+	current := r.current
+	limits := r.rateLimit.Get()
+
+	// The reloadable changes are rejected along with the port.
+	write("log:\n  level: debug\nserver:\n  port: 9999\nrate_limit:\n  default: 5/s\n")
+	require.ErrorIs(t, r.Reload(), errRestartRequired)
+
+	assert.Equal(t, zapcore.InfoLevel, logger.Level().Level())
+	assert.Equal(t, limits, r.rateLimit.Get())
+	assert.Same(t, current, r.current)
+}
+
+func TestReloadKeepsConfigOnInvalidFile(t *testing.T) {
+	r, write := newReloader(t, "log:\n  level: info\n")
+	current := r.current
+
+	write("log:\n  level: [debug\n")
+	require.Error(t, r.Reload())
+	write("log:\n  level: loud\n")
+	require.Error(t, r.Reload())
+
+	assert.Equal(t, zapcore.InfoLevel, logger.Level().Level())
+	assert.Same(t, current, r.current)
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,33 +14,183 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/godev/events-service/internal/config"
+	"github.com/godev/events-service/internal/health"
 )
 
-func RunServer(router *gin.Engine, port int, log *zap.Logger) {
-	addr := ":" + strconv.Itoa(port)
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: router,
-	}
+func RunServer(
+	router *gin.Engine,
+	grpcServer *grpc.Server,
+	admin http.Handler,
+	checker *health.Checker,
+	reload func() error,
+	stopStreams context.CancelFunc,
+	phases []shutdownPhase,
+	tlsConfig *tls.Config,
+	cfg *config.ServerConfig,
+	log *zap.Logger,
+) {
+	addr := ":" + strconv.Itoa(cfg.Port)
+	srv := newHTTPServer(addr, router, cfg)
+	srv.TLSConfig = tlsConfig
+	// Shutdown runs its hooks before waiting for the requests in flight.
+	// Cancelling the streams there lets the HTTP and gRPC servers drain.
+	srv.RegisterOnShutdown(stopStreams)
 
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
 
-	log.Info("Server started", zap.String("addr", addr))
+	log.Info("Server started",
+		zap.String("addr", addr),
+		zap.Bool("tls", srv.TLSConfig != nil),
+		zap.Bool("mutual_tls", cfg.TLS.ClientCAFile != ""))
+
+	grpcAddr := ":" + strconv.Itoa(cfg.GRPCPort)
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC", zap.Error(err))
+	}
+
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatal("Failed to start gRPC server", zap.Error(err))
+		}
+	}()
+
+	log.Info("gRPC server started", zap.String("addr", grpcAddr), zap.Bool("tls", tlsConfig != nil))
+
+	adminAddr := ":" + strconv.Itoa(cfg.AdminPort)
+	adminSrv := newHTTPServer(adminAddr, admin, cfg)
+
+	go func() {
+		if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start admin server", zap.Error(err))
+		}
+	}()
+
+	log.Info("Admin server started", zap.String("addr", adminAddr))
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Info("Shutting down server...")
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown", zap.Error(err))
+wait:
+	for {
+		select {
+		case <-hup:
+			log.Info("Reloading configuration")
+			if err := reload(); err != nil {
+				log.Error("Failed to reload configuration", zap.Error(err))
+			}
+		case <-quit:
+			break wait
+		}
 	}
 
+	checker.SetShuttingDown()
+	log.Info("Shutdown: reporting not ready, waiting for load balancers to stop sending requests",
+		zap.Duration("delay", cfg.ShutdownDelay),
+		zap.Time("deadline", time.Now().Add(cfg.ShutdownDelay)))
+	time.Sleep(cfg.ShutdownDelay)
+
+	// The servers are drained first and the admin server goes last, so
+	// that metrics can be scraped while the rest shuts down.
+	all := append([]shutdownPhase{
+		{name: "drain requests", timeout: cfg.ShutdownTimeout, run: drain(srv, grpcServer)},
+	}, phases...)
+	all = append(all, shutdownPhase{name: "stop admin server", timeout: cfg.CloseTimeout, run: adminSrv.Shutdown})
+	runPhases(log, all)
+
 	log.Info("Server exiting")
 }
+
+// drain closes the listeners and then waits for the requests in flight.
+// Watch streams are cancelled by the shutdown hook of srv; whatever is
+// still running when the deadline passes is cut off.
+func drain(srv *http.Server, grpcServer *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			_ = srv.Close()
+		}
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+		return err
+	}
+}
+
+// shutdownPhase is a step of the shutdown, such as stopping workers or
+// closing storage, that must be done within timeout.
+type shutdownPhase struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// runPhases runs the phases one after the other, also after one of them
+// failed or missed its deadline.
+func runPhases(log *zap.Logger, phases []shutdownPhase) {
+	for _, phase := range phases {
+		runPhase(log, phase)
+	}
+}
+
+// runPhase runs one shutdown phase, logging its deadline and outcome. A
+// phase that misses the deadline is left behind so that the shutdown goes
+// on.
+func runPhase(log *zap.Logger, phase shutdownPhase) {
+	ctx, cancel := context.WithTimeout(context.Background(), phase.timeout)
+	defer cancel()
+
+	deadline, _ := ctx.Deadline()
+	log.Info("Shutdown: "+phase.name, zap.Time("deadline", deadline))
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() { done <- phase.run(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Error("Shutdown phase failed", zap.String("phase", phase.name), zap.Duration("took", time.Since(start)), zap.Error(err))
+			return
+		}
+		log.Info("Shutdown phase finished", zap.String("phase", phase.name), zap.Duration("took", time.Since(start)))
+	case <-ctx.Done():
+		log.Error("Shutdown phase missed its deadline", zap.String("phase", phase.name), zap.Duration("timeout", phase.timeout))
+	}
+}
+
+// newHTTPServer applies the configured timeouts to a server for handler.
+func newHTTPServer(addr string, handler http.Handler, cfg *config.ServerConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
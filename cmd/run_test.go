@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	grpchandler "github.com/godev/events-service/internal/handler/grpc"
+	"github.com/godev/events-service/internal/middleware"
+)
+
+func TestRunPhases(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	ran := make(chan string, 4)
+	phase := func(name string, err error, block bool) shutdownPhase {
+		return shutdownPhase{name: name, timeout: 50 * time.Millisecond, run: func(ctx context.Context) error {
+			ran <- name
+			if block {
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond)
+			}
+			return err
+		}}
+	}
+	runPhases(zap.New(core), []shutdownPhase{
+		phase("drain requests", nil, false),
+		phase("stop background workers", nil, true),
+		phase("close storage", errors.New("closed twice"), false),
+		phase("stop admin server", nil, false),
+	})
+
+	// A phase that failed or missed its deadline does not stop the rest.
+	var order []string
+	for len(order) < 4 {
+		order = append(order, <-ran)
+	}
+	assert.Equal(t, []string{"drain requests", "stop background workers", "close storage", "stop admin server"}, order)
+
+	var outcomes []string
+	for _, entry := range logs.All() {
+		if phase, ok := entry.ContextMap()["phase"]; ok {
+			outcomes = append(outcomes, phase.(string)+": "+entry.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		"drain requests: Shutdown phase finished",
+		"stop background workers: Shutdown phase missed its deadline",
+		"close storage: Shutdown phase failed",
+		"stop admin server: Shutdown phase finished",
+	}, outcomes)
+}
+
+func TestDrainCancelsStreams(t *testing.T) {
+	streams, stopStreams := context.WithCancel(context.Background())
+	defer stopStreams()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	httpStarted := make(chan struct{})
+	router.GET("/watch", middleware.CancelWith(streams), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+		close(httpStarted)
+		<-c.Request.Context().Done()
+	})
+	srv := &http.Server{Handler: router}
+	srv.RegisterOnShutdown(stopStreams)
+	httpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(httpLis) }()
+
+	// The health service streams until the context of the stream ends,
+	// like Watch.
+	grpcServer := grpc.NewServer(grpc.StreamInterceptor(grpchandler.CancelStreamsWith(streams)))
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	grpcLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = grpcServer.Serve(grpcLis) }()
+
+	resp, err := http.Get("http://" + httpLis.Addr().String() + "/watch")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	<-httpStarted
+
+	conn, err := grpc.NewClient(grpcLis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, drain(srv, grpcServer)(ctx))
+	assert.Less(t, time.Since(start), time.Second, "the streams held up draining")
+
+	_, err = stream.Recv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/backoff"
+	"github.com/godev/events-service/internal/config"
+	"github.com/godev/events-service/internal/db"
+	"github.com/godev/events-service/internal/health"
+	"github.com/godev/events-service/internal/metrics"
+	"github.com/godev/events-service/internal/repository"
+	mongorepo "github.com/godev/events-service/internal/repository/mongo"
+	pgrepo "github.com/godev/events-service/internal/repository/postgres"
+	sqliterepo "github.com/godev/events-service/internal/repository/sqlite"
+	"github.com/godev/events-service/internal/retention"
+)
+
+// storage is what the configured backend provides to the server.
+type storage struct {
+	// mongo is nil unless the backend is MongoDB; features that need it
+	// refuse to start without it.
+	mongo *mongo.Database
+	// indexes builds the indexes of the mongo backend in the background;
+	// the indexes of features that need them are added before it runs.
+	indexes   *mongorepo.Indexer
+	events    repository.IEventRepository
+	audit     repository.IAuditRepository
+	retention retention.Store
+	// retentionLock is nil where a single instance owns the storage.
+	retentionLock retention.Lock
+	// archive keeps expired events next to the live ones, for
+	// RETENTION_ARCHIVE=collection.
+	archive retention.Archiver
+	// close releases the connections, waiting for operations in progress
+	// until ctx is done.
+	close func(ctx context.Context) error
+}
+
+// openStorage connects to the backend selected by STORAGE_BACKEND and
+// registers its readiness checks. MongoDB may still be down when it
+// returns, see db.NewMongoDB.
+func openStorage(cfg *config.Config, appMetrics *metrics.Metrics, checker *health.Checker, log *zap.Logger) (*storage, error) {
+	switch cfg.Storage.Backend {
+	case "mongo":
+		cfg.Mongo.Options.SetMonitor(db.CommandMonitors(appMetrics.CommandMonitor(), otelmongo.NewMonitor()))
+		mongodb, err := db.NewMongoDB(log, &cfg.Mongo)
+		if err != nil {
+			return nil, err
+		}
+		database := mongodb.GetDatabase()
+		retry := backoff.Backoff{Initial: cfg.Mongo.RetryInitialInterval, Max: cfg.Mongo.RetryMaxInterval}
+		s := &storage{
+			mongo:   database,
+			indexes: mongorepo.NewIndexer(database, retry, log),
+			close:   mongodb.Close,
+		}
+
+		checker.AddCheck("mongodb", mongodb.Ping)
+		checker.AddCheck("mongodb_primary", mongodb.PingPrimary)
+		checker.AddCheck("indexes", s.indexes.Check)
+
+		s.indexes.Add(mongorepo.EventIndexes()...)
+		s.indexes.Add(mongorepo.RetentionIndexes()...)
+		switch cfg.Storage.Mode {
+		case "state":
+			s.events = mongorepo.RequireIndexes(mongorepo.NewEventRepository(database, cfg.Mongo.Operations), s.indexes)
+		case "eventsourced":
+			s.indexes.Add(mongorepo.EventLogIndexes()...)
+			s.events = mongorepo.RequireIndexes(mongorepo.NewEventSourcedRepository(database, cfg.Mongo.Operations), s.indexes)
+		default:
+			_ = s.close(context.Background())
+			return nil, fmt.Errorf("unknown storage mode %q", cfg.Storage.Mode)
+		}
+		s.audit = mongorepo.NewAuditRepository(database, cfg.Mongo.Operations)
+		s.retention = mongorepo.NewRetentionStore(database)
+		s.retentionLock = mongorepo.NewRetentionLock(database, instanceName())
+		s.archive = mongorepo.NewCollectionArchiver(database)
+		return s, nil
+
+	case "sqlite":
+		if cfg.Storage.Mode != "state" {
+			return nil, fmt.Errorf("storage mode %q is not supported by SQLite", cfg.Storage.Mode)
+		}
+		sqlite, err := db.NewSQLite(log, &cfg.SQLite)
+		if err != nil {
+			return nil, err
+		}
+		checker.AddCheck("sqlite", sqlite.Ping)
+
+		database := sqlite.GetDB()
+		events, err := sqliterepo.NewEventRepository(database)
+		if err != nil {
+			_ = sqlite.Close()
+			return nil, err
+		}
+		return &storage{
+			events:    events,
+			audit:     sqliterepo.NewAuditRepository(database),
+			retention: sqliterepo.NewRetentionStore(database),
+			archive:   sqliterepo.NewTableArchiver(database),
+			close: func(context.Context) error {
+				return sqlite.Close()
+			},
+		}, nil
+
+	case "postgres":
+		if cfg.Storage.Mode != "state" {
+			return nil, fmt.Errorf("storage mode %q is not supported by PostgreSQL", cfg.Storage.Mode)
+		}
+		postgres, err := db.NewPostgres(log, &cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		checker.AddCheck("postgres", postgres.Ping)
+
+		pool := postgres.GetPool()
+		events, err := pgrepo.NewEventRepository(pool)
+		if err != nil {
+			postgres.Close()
+			return nil, err
+		}
+		return &storage{
+			events:        events,
+			audit:         pgrepo.NewAuditRepository(pool),
+			retention:     pgrepo.NewRetentionStore(pool),
+			retentionLock: pgrepo.NewRetentionLock(pool),
+			archive:       pgrepo.NewTableArchiver(pool),
+			close: func(context.Context) error {
+				postgres.Close()
+				return nil
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
@@ -0,0 +1,33 @@
+// Package apierror writes the error bodies of the REST API. They carry the
+// request ID so that a client can quote it when reporting a failure.
+package apierror
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/reqctx"
+)
+
+// Response is the body of an error answer to c.
+func Response(c *gin.Context, message string) model.ErrorResponse {
+	return model.ErrorResponse{
+		Message:   message,
+		RequestID: reqctx.RequestID(c.Request.Context()),
+	}
+}
+
+// Abort answers c with status and message and skips the remaining handlers.
+func Abort(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, Response(c, message))
+}
+
+// RetryAfter tells the client to retry after d, rounded up to whole
+// seconds as the header requires.
+func RetryAfter(c *gin.Context, d time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
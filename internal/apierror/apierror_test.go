@@ -0,0 +1,29 @@
+package apierror
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/godev/events-service/internal/reqctx"
+)
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request = c.Request.WithContext(reqctx.WithRequestID(c.Request.Context(), "req-1"))
+
+	RetryAfter(c, 1500*time.Millisecond)
+	Abort(c, http.StatusServiceUnavailable, "storage is unavailable")
+
+	assert.True(t, c.IsAborted())
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"), "rounded up to whole seconds")
+	assert.JSONEq(t, `{"message": "storage is unavailable", "requestId": "req-1"}`, w.Body.String())
+}
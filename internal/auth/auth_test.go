@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCredentials(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		apiKey        string
+		expected      Credentials
+	}{
+		{name: "api key header", apiKey: "secret", expected: Credentials{APIKey: "secret"}},
+		{name: "bearer jwt", authorization: "Bearer a.b.c", expected: Credentials{BearerToken: "a.b.c"}},
+		{name: "bearer api key", authorization: "bearer secret", expected: Credentials{APIKey: "secret"}},
+		{name: "other scheme", authorization: "Basic dXNlcjpwYXNz", expected: Credentials{}},
+		{name: "none", expected: Credentials{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseCredentials(tt.authorization, tt.apiKey))
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	keys := NewStaticKeyStore([]APIKey{
+		{Name: "reader", Hash: HashKey("read-key"), Scopes: []string{ScopeEventsRead}},
+		{Name: "revoked", Hash: HashKey("old-key"), Scopes: []string{ScopeAdmin}, Disabled: true},
+	})
+	a := NewAuthenticator(keys, nil)
+
+	p, err := a.Authenticate(context.Background(), Credentials{APIKey: "read-key"})
+	require.NoError(t, err)
+	assert.Equal(t, "reader", p.Subject)
+	assert.Equal(t, MethodAPIKey, p.Method)
+	assert.True(t, p.HasScope(ScopeEventsRead))
+	assert.False(t, p.HasScope(ScopeEventsWrite))
+
+	_, err = a.Authenticate(context.Background(), Credentials{APIKey: "old-key"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = a.Authenticate(context.Background(), Credentials{APIKey: "unknown"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = a.Authenticate(context.Background(), Credentials{})
+	assert.ErrorIs(t, err, ErrMissingCredentials)
+
+	_, err = a.Authenticate(context.Background(), Credentials{BearerToken: "a.b.c"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": "test",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwks, 0o600))
+
+	verifier, err := NewJWTVerifier(path, "https://issuer.example", "events-service")
+	require.NoError(t, err)
+	a := NewAuthenticator(nil, verifier)
+
+	sign := func(signer *rsa.PrivateKey, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		s, err := token.SignedString(signer)
+		require.NoError(t, err)
+		return s
+	}
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "ci-pipeline",
+			"iss":   "https://issuer.example",
+			"aud":   "events-service",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "events:read events:write",
+		}
+	}
+
+	p, err := a.Authenticate(context.Background(), Credentials{BearerToken: sign(key, valid())})
+	require.NoError(t, err)
+	assert.Equal(t, "ci-pipeline", p.Subject)
+	assert.Equal(t, MethodJWT, p.Method)
+	assert.True(t, p.HasScope(ScopeEventsWrite))
+	assert.False(t, p.HasScope(ScopeAdmin))
+
+	expired := valid()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	wrongIssuer := valid()
+	wrongIssuer["iss"] = "https://evil.example"
+	noExpiry := valid()
+	delete(noExpiry, "exp")
+
+	for name, token := range map[string]string{
+		"expired":      sign(key, expired),
+		"wrong issuer": sign(key, wrongIssuer),
+		"no expiry":    sign(key, noExpiry),
+		"wrong key":    sign(otherKey, valid()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.Authenticate(context.Background(), Credentials{BearerToken: token})
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+		})
+	}
+}
+
+func TestAuthenticateClientCert(t *testing.T) {
+	keys := NewStaticKeyStore([]APIKey{{Name: "reader", Hash: HashKey("read-key"), Scopes: []string{ScopeEventsRead}}})
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "deployer", Organization: []string{"acme"}}}
+
+	a := NewAuthenticator(keys, nil)
+	_, err := a.Authenticate(context.Background(), Credentials{ClientCert: cert})
+	assert.ErrorIs(t, err, ErrMissingCredentials, "certificates are ignored unless accepted")
+
+	a.AcceptClientCerts([]string{ScopeEventsWrite})
+	p, err := a.Authenticate(context.Background(), Credentials{ClientCert: cert})
+	require.NoError(t, err)
+	assert.Equal(t, "deployer", p.Subject)
+	assert.Equal(t, MethodClientCert, p.Method)
+	assert.Equal(t, "acme", p.Tenant, "the organization is the tenant")
+	assert.True(t, p.HasScope(ScopeEventsWrite))
+
+	for name, subject := range map[string]pkix.Name{
+		"no organization":    {CommonName: "deployer"},
+		"invalid tenant":     {CommonName: "deployer", Organization: []string{"Acme Inc."}},
+		"empty organization": {CommonName: "deployer", Organization: []string{""}},
+	} {
+		_, err := a.Authenticate(context.Background(), Credentials{ClientCert: &x509.Certificate{Subject: subject}})
+		assert.ErrorIs(t, err, ErrInvalidCredentials, name)
+	}
+
+	// Other credentials win over the certificate.
+	p, err = a.Authenticate(context.Background(), Credentials{APIKey: "read-key", ClientCert: cert})
+	require.NoError(t, err)
+	assert.Equal(t, "reader", p.Subject)
+
+	assert.Equal(t, "O=acme", CertSubject(&x509.Certificate{Subject: pkix.Name{Organization: []string{"acme"}}}))
+}
+
+func TestAnonymousAuthenticator(t *testing.T) {
+	p, err := NewAnonymousAuthenticator([]string{ScopeEventsRead, ScopeEventsWrite}).Authenticate(context.Background(), Credentials{})
+	require.NoError(t, err)
+	assert.Equal(t, "anonymous", p.Subject)
+	assert.Equal(t, MethodAnonymous, p.Method)
+	assert.True(t, p.HasScope(ScopeEventsWrite))
+	assert.False(t, p.HasScope(ScopeAuditRead))
+	assert.False(t, p.HasScope(ScopeAdmin))
+
+	p, err = NewAnonymousAuthenticator([]string{ScopeAdmin}).Authenticate(context.Background(), Credentials{})
+	require.NoError(t, err)
+	assert.True(t, p.HasScope(ScopeAuditRead), "the scopes are configured")
+}
+
+func TestAnonymousAuthenticatorWithClientCerts(t *testing.T) {
+	a := NewAnonymousAuthenticator([]string{ScopeEventsRead, ScopeEventsWrite})
+	a.AcceptClientCerts([]string{ScopeEventsRead})
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "deployer", Organization: []string{"acme"}}}
+
+	p, err := a.Authenticate(context.Background(), Credentials{ClientCert: cert})
+	require.NoError(t, err)
+	assert.Equal(t, "deployer", p.Subject, "the certificate names the actor without authentication")
+	assert.Equal(t, MethodClientCert, p.Method)
+	assert.Equal(t, "acme", p.Tenant)
+
+	p, err = a.Authenticate(context.Background(), Credentials{})
+	require.NoError(t, err)
+	assert.Equal(t, MethodAnonymous, p.Method)
+}
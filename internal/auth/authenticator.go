@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godev/events-service/internal/tenant"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Credentials are what the caller presented with a request.
+type Credentials struct {
+	APIKey      string
+	BearerToken string
+	// ClientCert is the client certificate verified by mutual TLS.
+	ClientCert *x509.Certificate
+}
+
+// ParseCredentials extracts credentials from the Authorization and
+// X-API-Key header values. A bearer token that is not a JWT is treated as
+// an API key, so clients that only support bearer auth can use keys too.
+func ParseCredentials(authorization, apiKey string) Credentials {
+	creds := Credentials{APIKey: strings.TrimSpace(apiKey)}
+
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return creds
+	}
+	token = strings.TrimSpace(token)
+	if strings.Count(token, ".") == 2 {
+		creds.BearerToken = token
+	} else if creds.APIKey == "" {
+		creds.APIKey = token
+	}
+	return creds
+}
+
+type Authenticator struct {
+	keys     KeyStore
+	verifier *JWTVerifier
+	// certs accepts client certificates, granting them certScopes.
+	certs      bool
+	certScopes []string
+	// anonymous is the principal of every request without a client
+	// certificate when authentication is disabled.
+	anonymous *Principal
+}
+
+// NewAuthenticator accepts API keys from keys and JWTs verified by
+// verifier; either may be nil to turn that method off.
+func NewAuthenticator(keys KeyStore, verifier *JWTVerifier) *Authenticator {
+	return &Authenticator{keys: keys, verifier: verifier}
+}
+
+// AcceptClientCerts lets callers that present no other credentials
+// authenticate with their verified client certificate, granting them scopes
+// in the tenant named by the certificate; see CertTenant.
+func (a *Authenticator) AcceptClientCerts(scopes []string) {
+	a.certs, a.certScopes = true, scopes
+}
+
+// NewAnonymousAuthenticator lets every request through as an anonymous
+// principal with scopes, except that callers with a verified client
+// certificate are still identified by it once AcceptClientCerts is called.
+func NewAnonymousAuthenticator(scopes []string) *Authenticator {
+	return &Authenticator{anonymous: Anonymous(scopes)}
+}
+
+// Authenticate returns ErrMissingCredentials or ErrInvalidCredentials when
+// the caller cannot be identified; any other error means the key store
+// failed.
+func (a *Authenticator) Authenticate(ctx context.Context, creds Credentials) (*Principal, error) {
+	if a.anonymous != nil {
+		if creds.ClientCert != nil && a.certs {
+			return a.certPrincipal(creds.ClientCert)
+		}
+		return a.anonymous, nil
+	}
+
+	switch {
+	case creds.BearerToken != "":
+		if a.verifier == nil {
+			return nil, ErrInvalidCredentials
+		}
+		p, err := a.verifier.Verify(creds.BearerToken)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidCredentials, err)
+		}
+		return p, nil
+	case creds.APIKey != "":
+		if a.keys == nil {
+			return nil, ErrInvalidCredentials
+		}
+		key, err := a.keys.Lookup(ctx, HashKey(creds.APIKey))
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return &Principal{Subject: key.Name, Scopes: key.Scopes, Tenant: key.Tenant, Method: MethodAPIKey}, nil
+	case creds.ClientCert != nil && a.certs:
+		return a.certPrincipal(creds.ClientCert)
+	default:
+		return nil, ErrMissingCredentials
+	}
+}
+
+func (a *Authenticator) certPrincipal(cert *x509.Certificate) (*Principal, error) {
+	bound, err := CertTenant(cert)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidCredentials, err)
+	}
+	return &Principal{Subject: CertSubject(cert), Scopes: a.certScopes, Tenant: bound, Method: MethodClientCert}, nil
+}
+
+// CertSubject names the holder of cert: its common name, or the whole
+// subject when the common name is empty.
+func CertSubject(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
+
+// CertTenant returns the tenant of cert: the first organization (O) of its
+// subject. Every CA-signed certificate would otherwise share the default
+// tenant, so a certificate without one is not accepted.
+func CertTenant(cert *x509.Certificate) (string, error) {
+	if len(cert.Subject.Organization) == 0 {
+		return "", errors.New("client certificate has no organization to take the tenant from")
+	}
+	name := cert.Subject.Organization[0]
+	if err := tenant.Validate(name); err != nil {
+		return "", fmt.Errorf("client certificate organization %q: %w", name, err)
+	}
+	return name, nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// APIKey is a stored API key. Only the SHA-256 hash of the key is kept.
+type APIKey struct {
+	Name     string   `bson:"name"`
+	Hash     string   `bson:"hash"`
+	Scopes   []string `bson:"scopes"`
+	Tenant   string   `bson:"tenant,omitempty"`
+	Disabled bool     `bson:"disabled"`
+}
+
+// KeyStore looks up API keys by hash. Lookup returns nil, nil when no
+// enabled key has that hash.
+type KeyStore interface {
+	Lookup(ctx context.Context, hash string) (*APIKey, error)
+}
+
+// HashKey returns the hex-encoded SHA-256 hash under which key is stored.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type staticKeyStore struct {
+	keys []APIKey
+}
+
+// NewStaticKeyStore serves the keys configured at startup.
+func NewStaticKeyStore(keys []APIKey) KeyStore {
+	return &staticKeyStore{keys: keys}
+}
+
+func (s *staticKeyStore) Lookup(_ context.Context, hash string) (*APIKey, error) {
+	var found *APIKey
+	// Every key is compared so that timing does not reveal which one matched.
+	for i := range s.keys {
+		key := &s.keys[i]
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) == 1 && !key.Disabled {
+			found = key
+		}
+	}
+	return found, nil
+}
+
+type multiKeyStore []KeyStore
+
+// MultiKeyStore returns the first match from stores, in order.
+func MultiKeyStore(stores ...KeyStore) KeyStore {
+	return multiKeyStore(stores)
+}
+
+func (m multiKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	for _, store := range m {
+		key, err := store.Lookup(ctx, hash)
+		if err != nil || key != nil {
+			return key, err
+		}
+	}
+	return nil, nil
+}
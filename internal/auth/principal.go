@@ -0,0 +1,90 @@
+// Package auth authenticates callers with API keys or JWT bearer tokens and
+// describes what they are allowed to do.
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/godev/events-service/internal/tenant"
+)
+
+const (
+	ScopeEventsRead  = "events:read"
+	ScopeEventsWrite = "events:write"
+	ScopeAuditRead   = "audit:read"
+	// ScopeAdmin grants access to administrative endpoints and implies
+	// every other scope.
+	ScopeAdmin = "admin"
+)
+
+const (
+	MethodAPIKey     = "api_key"
+	MethodJWT        = "jwt"
+	MethodClientCert = "client_cert"
+	MethodAnonymous  = "anonymous"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject is the API key name, the token "sub" claim or the subject of
+	// the client certificate.
+	Subject string
+	Scopes  []string
+	// Tenant binds the principal to one tenant. Unbound principals work in
+	// the default tenant, except admins, who may choose any tenant with the
+	// X-Tenant-ID header.
+	Tenant string
+	// Method tells how the principal was authenticated.
+	Method string
+}
+
+// Anonymous returns the principal of the requests made without credentials
+// when authentication is disabled, granted scopes. With ScopeAdmin it may
+// also choose its tenant.
+func Anonymous(scopes []string) *Principal {
+	return &Principal{Subject: "anonymous", Scopes: scopes, Method: MethodAnonymous}
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+var ErrTenantForbidden = errors.New("credentials are not valid for this tenant")
+
+// ResolveTenant returns the tenant a request of p operates in, given the
+// tenant it asked for (possibly empty).
+func (p *Principal) ResolveTenant(requested string) (string, error) {
+	bound := p.Tenant
+	if bound == "" && !p.HasScope(ScopeAdmin) {
+		bound = tenant.Default
+	}
+	if bound != "" {
+		if requested != "" && requested != bound {
+			return "", ErrTenantForbidden
+		}
+		return bound, nil
+	}
+
+	if requested == "" {
+		return tenant.Default, nil
+	}
+	if err := tenant.Validate(requested); err != nil {
+		return "", err
+	}
+	return requested, nil
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller ctx belongs to, or nil outside of
+// an authenticated request.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,82 @@
+// Package backoff retries operations with waits that double after every
+// failure, for dependencies that may come up after the service does.
+package backoff
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff is the schedule of waits between attempts. The zero value is not
+// usable; Initial must be positive.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// Wait returns the wait after the given number of failed attempts,
+// starting at one. It doubles from Initial up to Max and is jittered by up
+// to a fifth either way so that replicas do not retry in step.
+func (b Backoff) Wait(attempt int) time.Duration {
+	d := b.Initial
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	jitter := int64(d) / 5
+	if jitter > 0 {
+		d += time.Duration(rand.Int64N(2*jitter+1) - jitter)
+	}
+	return d
+}
+
+// permanentError is a failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as a failure that retrying cannot fix, so that Retry
+// gives up on it at once.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Retry calls op until it succeeds, fails with an error marked Permanent or
+// ctx is done, waiting between attempts. failed is told about every failure
+// that is retried and the wait that follows it. Retry returns nil once op
+// succeeds, else the last error of op without the Permanent mark.
+func Retry(ctx context.Context, b Backoff, op func(context.Context) error, failed func(attempt int, err error, wait time.Duration)) error {
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		wait := b.Wait(attempt)
+		if failed != nil {
+			failed(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
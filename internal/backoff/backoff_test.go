@@ -0,0 +1,70 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWait(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second}
+
+	within := func(attempt int, want time.Duration) {
+		t.Helper()
+		got := b.Wait(attempt)
+		assert.GreaterOrEqual(t, got, want-want/5, "attempt %d", attempt)
+		assert.LessOrEqual(t, got, want+want/5, "attempt %d", attempt)
+	}
+	within(1, 100*time.Millisecond)
+	within(2, 200*time.Millisecond)
+	within(4, 800*time.Millisecond)
+	within(5, time.Second)
+	within(100, time.Second)
+}
+
+func TestRetry(t *testing.T) {
+	b := Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond}
+	errDown := errors.New("down")
+	errBroken := errors.New("broken")
+
+	t.Run("until success", func(t *testing.T) {
+		calls := 0
+		var failures []int
+		err := Retry(context.Background(), b, func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return errDown
+			}
+			return nil
+		}, func(attempt int, err error, _ time.Duration) {
+			assert.ErrorIs(t, err, errDown)
+			failures = append(failures, attempt)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, []int{1, 2}, failures)
+	})
+
+	t.Run("until a permanent failure", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), b, func(context.Context) error {
+			calls++
+			if calls < 2 {
+				return errDown
+			}
+			return Permanent(errBroken)
+		}, nil)
+		assert.Same(t, errBroken, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("until the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := Retry(ctx, b, func(context.Context) error { return errDown }, nil)
+		assert.ErrorIs(t, err, errDown)
+	})
+}
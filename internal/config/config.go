@@ -1,73 +1,341 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Every setting has a key in the configuration file (its yaml tag, nested
+// by section), an environment variable (its env tag) and a command line
+// flag named after the dotted key, e.g. --mongo.max_pool_size. See Load.
+// Settings tagged reload may change while the service runs; see Diff.
+
 type MongoConfig struct {
-	URI      string
-	Database string
-	Options  *options.ClientOptions
+	URI      string `yaml:"uri" env:"MONGODB_URI"`
+	Database string `yaml:"database" env:"MONGODB_DATABASE"`
+	// MaxPoolSize and MinPoolSize bound the connections kept per server;
+	// MaxConnecting limits how many are established at once, zero leaving
+	// the driver default.
+	MaxPoolSize   uint64 `yaml:"max_pool_size" env:"MONGODB_MAX_POOL_SIZE"`
+	MinPoolSize   uint64 `yaml:"min_pool_size" env:"MONGODB_MIN_POOL_SIZE"`
+	MaxConnecting uint64 `yaml:"max_connecting" env:"MONGODB_MAX_CONNECTING"`
+	// MaxConnIdleTime closes connections idle for longer; zero keeps them.
+	MaxConnIdleTime        time.Duration `yaml:"max_conn_idle_time" env:"MONGODB_MAX_CONN_IDLE_TIME"`
+	ConnectTimeout         time.Duration `yaml:"connect_timeout" env:"MONGODB_CONNECT_TIMEOUT"`
+	ServerSelectionTimeout time.Duration `yaml:"server_selection_timeout" env:"MONGODB_SERVER_SELECTION_TIMEOUT"`
+	HeartbeatInterval      time.Duration `yaml:"heartbeat_interval" env:"MONGODB_HEARTBEAT_INTERVAL"`
+	// Timeout bounds operations that have no deadline of their own; zero
+	// leaves them unbounded.
+	Timeout time.Duration `yaml:"timeout" env:"MONGODB_TIMEOUT"`
+	// ReadPreference, ReadConcern and WriteConcern (w: "majority" or a
+	// number of members) apply to every operation; empty leaves what the
+	// URI or the server sets. WriteJournal and WriteTimeout complete the
+	// write concern.
+	ReadPreference string        `yaml:"read_preference" env:"MONGODB_READ_PREFERENCE"`
+	ReadConcern    string        `yaml:"read_concern" env:"MONGODB_READ_CONCERN"`
+	WriteConcern   string        `yaml:"write_concern" env:"MONGODB_WRITE_CONCERN"`
+	WriteJournal   bool          `yaml:"write_journal" env:"MONGODB_WRITE_JOURNAL"`
+	WriteTimeout   time.Duration `yaml:"write_timeout" env:"MONGODB_WRITE_TIMEOUT"`
+	// ReadPreferences, ReadConcerns and WriteConcerns override the settings
+	// above for the operations named in operation.Reads and
+	// operation.Writes, e.g. "list=secondaryPreferred".
+	ReadPreferences StringMap `yaml:"read_preferences" env:"MONGODB_READ_PREFERENCES"`
+	ReadConcerns    StringMap `yaml:"read_concerns" env:"MONGODB_READ_CONCERNS"`
+	WriteConcerns   StringMap `yaml:"write_concerns" env:"MONGODB_WRITE_CONCERNS"`
+	// StartupTimeout is how long startup waits for the deployment to
+	// answer. Past it the server starts degraded, answering 503 until
+	// MongoDB is reachable, unless DegradedStart is off.
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"MONGODB_STARTUP_TIMEOUT"`
+	DegradedStart  bool          `yaml:"degraded_start" env:"MONGODB_DEGRADED_START"`
+	// RetryInitialInterval and RetryMaxInterval bound the waits between
+	// attempts to reach the deployment and to build the indexes, which
+	// double after every failure.
+	RetryInitialInterval time.Duration `yaml:"retry_initial_interval" env:"MONGODB_RETRY_INITIAL_INTERVAL"`
+	RetryMaxInterval     time.Duration `yaml:"retry_max_interval" env:"MONGODB_RETRY_MAX_INTERVAL"`
+	// MigrateOnStartup applies the pending schema migrations before the
+	// indexes are built; when off, run the migrate command instead.
+	MigrateOnStartup bool `yaml:"migrate_on_startup" env:"MONGODB_MIGRATE_ON_STARTUP"`
+	// Options are the client options built from the settings above by Load.
+	Options *options.ClientOptions `yaml:"-"`
+	// Operations are the collection options of the operations with
+	// overrides, built by Load.
+	Operations map[string]*options.CollectionOptions `yaml:"-"`
+}
+
+// clientOptions builds the driver options. The settings above win over the
+// same options given in the URI.
+func (c *MongoConfig) clientOptions() *options.ClientOptions {
+	opts := options.Client().
+		ApplyURI(c.URI).
+		SetConnectTimeout(c.ConnectTimeout).
+		SetServerSelectionTimeout(c.ServerSelectionTimeout).
+		SetHeartbeatInterval(c.HeartbeatInterval).
+		SetMaxPoolSize(c.MaxPoolSize).
+		SetMinPoolSize(c.MinPoolSize)
+	if c.MaxConnecting > 0 {
+		opts.SetMaxConnecting(c.MaxConnecting)
+	}
+	if c.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(c.MaxConnIdleTime)
+	}
+	if c.Timeout > 0 {
+		opts.SetTimeout(c.Timeout)
+	}
+	// Invalid values are reported by Validate.
+	if rp, err := readPreference(c.ReadPreference); err == nil && rp != nil {
+		opts.SetReadPreference(rp)
+	}
+	if rc, err := readConcern(c.ReadConcern); err == nil && rc != nil {
+		opts.SetReadConcern(rc)
+	}
+	if wc, err := c.writeConcern(c.WriteConcern); err == nil && wc != nil {
+		opts.SetWriteConcern(wc)
+	}
+	return opts
+}
+
+type SQLiteConfig struct {
+	// Path is the database file; it is created when missing.
+	Path string `yaml:"path" env:"SQLITE_PATH"`
+}
+
+type PostgresConfig struct {
+	// URL is a libpq connection URL; pool settings such as pool_max_conns
+	// go into its query.
+	URL string `yaml:"url" env:"POSTGRES_URL"`
+}
+
+type StorageConfig struct {
+	// Backend is "mongo", "sqlite" or "postgres".
+	Backend string `yaml:"backend" env:"STORAGE_BACKEND"`
+	// Mode is "state" to update events in place or "eventsourced" to append
+	// every change to a log and keep events as its projection. Only the
+	// mongo backend can be event-sourced.
+	Mode string `yaml:"mode" env:"STORAGE_MODE"`
+}
+
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM certificate and key of the HTTP and
+	// gRPC servers; when both are empty they serve without TLS. Both are
+	// read again when they change on disk.
+	CertFile string `yaml:"cert_file" env:"SERVER_TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"SERVER_TLS_KEY_FILE"`
+	// ClientCAFile is a PEM bundle of the CAs that sign client certificates;
+	// setting it turns on mutual TLS.
+	ClientCAFile string `yaml:"client_ca_file" env:"SERVER_TLS_CLIENT_CA_FILE"`
+	// ClientAuth is "require" to reject clients without a certificate or
+	// "optional" to verify only the certificates that are presented.
+	ClientAuth string `yaml:"client_auth" env:"SERVER_TLS_CLIENT_AUTH"`
+	// MinVersion is the lowest TLS version accepted, "1.2" or "1.3".
+	MinVersion string `yaml:"min_version" env:"SERVER_TLS_MIN_VERSION"`
 }
 
 type ServerConfig struct {
-	Port     int
-	LogLevel string
+	Port      int `yaml:"port" env:"SERVER_PORT"`
+	GRPCPort  int `yaml:"grpc_port" env:"GRPC_PORT"`
+	AdminPort int `yaml:"admin_port" env:"ADMIN_PORT"`
+	// ShutdownDelay is how long the server keeps serving after reporting
+	// not-ready on SIGTERM, giving load balancers time to drain it.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+	// ShutdownTimeout is how long requests in flight may take to finish
+	// once the servers stopped accepting new ones.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// CloseTimeout bounds each later shutdown phase: stopping background
+	// workers and closing the storage.
+	CloseTimeout time.Duration `yaml:"close_timeout" env:"SHUTDOWN_CLOSE_TIMEOUT"`
+	// The timeouts of the HTTP servers; zero disables one. WriteTimeout
+	// also cuts /v1/watch streams, so it is off by default.
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// MaxHeaderBytes limits the request line and headers, MaxBodyBytes the
+	// body of API requests.
+	MaxHeaderBytes int `yaml:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	MaxBodyBytes   int `yaml:"max_body_bytes" env:"SERVER_MAX_BODY_BYTES"`
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key are replayed; at most IdempotencyMaxKeys are kept.
+	IdempotencyTTL     time.Duration `yaml:"idempotency_ttl" env:"SERVER_IDEMPOTENCY_TTL"`
+	IdempotencyMaxKeys int           `yaml:"idempotency_max_keys" env:"SERVER_IDEMPOTENCY_MAX_KEYS"`
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed. The client address
+	// keys the rate limits of anonymous callers and is recorded in the audit
+	// log, so by default no proxy is trusted and the peer address is used.
+	TrustedProxies []string  `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+	TLS            TLSConfig `yaml:"tls"`
 }
 
-type Config struct {
-	Mongo  MongoConfig
-	Server ServerConfig
+type LogConfig struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" reload:"true"`
+	// Encoding is either "json" or "console".
+	Encoding string `yaml:"encoding" env:"LOG_ENCODING"`
+	// SamplingInitial and SamplingThereafter limit repeated messages per
+	// second: the first SamplingInitial entries with the same level and
+	// message are logged, then every SamplingThereafter-th. Zero disables
+	// sampling.
+	SamplingInitial    int `yaml:"sampling_initial" env:"LOG_SAMPLING_INITIAL"`
+	SamplingThereafter int `yaml:"sampling_thereafter" env:"LOG_SAMPLING_THEREAFTER"`
+}
+
+type TracingConfig struct {
+	// Exporter is one of "none", "otlp" or "stdout".
+	Exporter     string  `yaml:"exporter" env:"TRACING_EXPORTER"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"TRACING_OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName  string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
 }
 
-func New() *Config {
-	mongoURI := getEnv("MONGODB_URI", "mongodb://localhost:27017").(string)
-	mongoDB := getEnv("MONGODB_DATABASE", "events").(string)
-	serverPort := getEnv("SERVER_PORT", 8080).(int)
-	logLevel := getEnv("LOG_LEVEL", "info").(string)
+type APIKeyConfig struct {
+	Name string `yaml:"name"`
+	// Hash is the hex-encoded SHA-256 of the key.
+	Hash   string   `yaml:"hash"`
+	Scopes []string `yaml:"scopes"`
+	// Tenant binds the key to one tenant; empty lets it choose any.
+	Tenant string `yaml:"tenant,omitempty"`
+}
+
+type AuthConfig struct {
+	// Enabled turns authentication on; otherwise every request without a
+	// client certificate is served as anonymous with AnonymousScopes.
+	Enabled bool `yaml:"enabled" env:"AUTH_ENABLED"`
+	// AnonymousScopes are granted to anonymous callers; add audit:read or
+	// admin to reach the audit log and the administration routes without
+	// authentication.
+	AnonymousScopes []string `yaml:"anonymous_scopes" env:"AUTH_ANONYMOUS_SCOPES"`
+	APIKeys         APIKeys  `yaml:"api_keys" env:"AUTH_API_KEYS"`
+	// APIKeysCollection is the MongoDB collection with additional API keys;
+	// empty disables it.
+	APIKeysCollection string `yaml:"api_keys_collection" env:"AUTH_API_KEYS_COLLECTION"`
+	// JWKSFile is the path to the JWKS used to verify bearer tokens; empty
+	// disables JWT authentication.
+	JWKSFile    string `yaml:"jwks_file" env:"AUTH_JWKS_FILE"`
+	JWTIssuer   string `yaml:"jwt_issuer" env:"AUTH_JWT_ISSUER"`
+	JWTAudience string `yaml:"jwt_audience" env:"AUTH_JWT_AUDIENCE"`
+	// ClientCertScopes are granted to callers authenticated by a client
+	// certificate under mutual TLS, in the tenant named by the organization
+	// (O) of the certificate. Certificates identify callers also when
+	// authentication is disabled.
+	ClientCertScopes []string `yaml:"client_cert_scopes" env:"AUTH_CLIENT_CERT_SCOPES"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	// Backend is "memory" to limit each instance on its own or "mongo" to
+	// share the limits between replicas.
+	Backend string `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+	// Collection holds the buckets of the mongo backend.
+	Collection string `yaml:"collection" env:"RATE_LIMIT_COLLECTION"`
+	// Default applies to routes without a limit of their own, e.g. "20/s:40";
+	// empty leaves them unlimited.
+	Default string `yaml:"default" env:"RATE_LIMIT_DEFAULT" reload:"true"`
+	// Routes maps route paths such as "/v1/start", and full gRPC method
+	// names such as "/events.v1.EventService/Start", to their limit.
+	Routes StringMap `yaml:"routes" env:"RATE_LIMIT_ROUTES" reload:"true"`
+}
 
-	clientOptions := options.Client().
-		ApplyURI(mongoURI).
-		SetConnectTimeout(10 * time.Second).
-		SetServerSelectionTimeout(10 * time.Second).
-		SetMaxPoolSize(100)
+type RetentionConfig struct {
+	// Default is how long finished events are kept, e.g. "90d"; empty keeps
+	// them forever.
+	Default string `yaml:"default" env:"RETENTION_DEFAULT" reload:"true"`
+	// Types maps event types to their own retention.
+	Types StringMap `yaml:"types" env:"RETENTION_TYPES" reload:"true"`
+	// Interval is how often expired events are purged.
+	Interval time.Duration `yaml:"interval" env:"RETENTION_INTERVAL"`
+	// Archive is "none", "collection" to move expired events to the
+	// events_archive collection (a table with SQL backends), or "file" to
+	// write them to ArchiveDir.
+	Archive    string `yaml:"archive" env:"RETENTION_ARCHIVE"`
+	ArchiveDir string `yaml:"archive_dir" env:"RETENTION_ARCHIVE_DIR"`
+	BatchSize  int    `yaml:"batch_size" env:"RETENTION_BATCH_SIZE"`
+}
 
+type Config struct {
+	Mongo     MongoConfig     `yaml:"mongo"`
+	SQLite    SQLiteConfig    `yaml:"sqlite"`
+	Postgres  PostgresConfig  `yaml:"postgres"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Server    ServerConfig    `yaml:"server"`
+	Log       LogConfig       `yaml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Auth      AuthConfig      `yaml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Retention RetentionConfig `yaml:"retention"`
+}
+
+// Default returns the configuration used for everything that is not set.
+func Default() *Config {
 	return &Config{
 		Mongo: MongoConfig{
-			URI:      mongoURI,
-			Database: mongoDB,
-			Options:  clientOptions,
+			URI:                    "mongodb://localhost:27017",
+			Database:               "events",
+			MaxPoolSize:            100,
+			ConnectTimeout:         10 * time.Second,
+			ServerSelectionTimeout: 10 * time.Second,
+			HeartbeatInterval:      10 * time.Second,
+			StartupTimeout:         30 * time.Second,
+			DegradedStart:          true,
+			RetryInitialInterval:   500 * time.Millisecond,
+			RetryMaxInterval:       30 * time.Second,
+			MigrateOnStartup:       true,
+		},
+		SQLite: SQLiteConfig{
+			Path: "events.db",
+		},
+		Postgres: PostgresConfig{
+			URL: "postgres://localhost:5432/events?sslmode=disable",
+		},
+		Storage: StorageConfig{
+			Backend: "mongo",
+			Mode:    "state",
 		},
 		Server: ServerConfig{
-			Port:     serverPort,
-			LogLevel: logLevel,
+			Port:               8080,
+			GRPCPort:           9090,
+			AdminPort:          9091,
+			ShutdownDelay:      5 * time.Second,
+			ShutdownTimeout:    10 * time.Second,
+			CloseTimeout:       5 * time.Second,
+			ReadHeaderTimeout:  10 * time.Second,
+			ReadTimeout:        30 * time.Second,
+			IdleTimeout:        2 * time.Minute,
+			MaxHeaderBytes:     1 << 20,
+			MaxBodyBytes:       1 << 20,
+			IdempotencyTTL:     24 * time.Hour,
+			IdempotencyMaxKeys: 100000,
+			TrustedProxies:     []string{},
+			TLS: TLSConfig{
+				ClientAuth: "require",
+				MinVersion: "1.2",
+			},
+		},
+		Log: LogConfig{
+			Level:              "info",
+			Encoding:           "json",
+			SamplingInitial:    100,
+			SamplingThereafter: 100,
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4317",
+			OTLPInsecure: true,
+			SampleRatio:  1.0,
+			ServiceName:  "events-service",
+		},
+		Auth: AuthConfig{
+			AnonymousScopes:  []string{"events:read", "events:write"},
+			ClientCertScopes: []string{"events:read", "events:write"},
+		},
+		RateLimit: RateLimitConfig{
+			Backend:    "memory",
+			Collection: "rate_limits",
+		},
+		Retention: RetentionConfig{
+			Interval:   time.Hour,
+			Archive:    "none",
+			ArchiveDir: "archive",
+			BatchSize:  1000,
 		},
-	}
-}
-
-func getEnv(key string, def interface{}) interface{} {
-	switch defTyped := def.(type) {
-	case string:
-		if val, ok := os.LookupEnv(key); ok {
-			return val
-		}
-		return defTyped
-	case int:
-		if val, ok := os.LookupEnv(key); ok {
-			intVal, err := strconv.Atoi(val)
-			if err != nil {
-				log.Printf("Warning: could not parse %s as int, using default %d", key, defTyped)
-				return defTyped
-			}
-			return intVal
-		}
-		return defTyped
-	default:
-		return def
 	}
 }
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+
+	"github.com/godev/events-service/internal/operation"
+)
+
+var readConcernLevels = []string{"local", "available", "majority", "linearizable", "snapshot"}
+
+// readPreference parses a mode such as "secondaryPreferred"; empty is nil.
+func readPreference(mode string) (*readpref.ReadPref, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	m, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, err
+	}
+	return readpref.New(m)
+}
+
+// readConcern parses a level such as "majority"; empty is nil.
+func readConcern(level string) (*readconcern.ReadConcern, error) {
+	if level == "" {
+		return nil, nil
+	}
+	if !slices.Contains(readConcernLevels, level) {
+		return nil, fmt.Errorf("read concern %q is not one of %q", level, readConcernLevels)
+	}
+	return &readconcern.ReadConcern{Level: level}, nil
+}
+
+// writeConcern builds the write concern with w and the journal and timeout
+// settings; it is nil when none of them is set.
+func (c *MongoConfig) writeConcern(w string) (*writeconcern.WriteConcern, error) {
+	if w == "" && !c.WriteJournal && c.WriteTimeout == 0 {
+		return nil, nil
+	}
+
+	wc := &writeconcern.WriteConcern{WTimeout: c.WriteTimeout}
+	if c.WriteJournal {
+		journal := true
+		wc.Journal = &journal
+	}
+	switch w {
+	case "":
+	case "majority":
+		wc.W = w
+	default:
+		n, err := strconv.Atoi(w)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("write concern %q is neither majority nor a number of members", w)
+		}
+		wc.W = n
+	}
+	return wc, nil
+}
+
+// operationOptions builds the collection options of every operation that
+// has an override. Settings without an override are inherited from the
+// client.
+func (c *MongoConfig) operationOptions() (map[string]*options.CollectionOptions, error) {
+	ops := make(map[string]*options.CollectionOptions)
+	get := func(op string) *options.CollectionOptions {
+		if ops[op] == nil {
+			ops[op] = options.Collection()
+		}
+		return ops[op]
+	}
+
+	var errs []error
+	for _, op := range slices.Sorted(maps.Keys(c.ReadPreferences)) {
+		mode := c.ReadPreferences[op]
+		if !slices.Contains(operation.Reads, op) {
+			errs = append(errs, fmt.Errorf("mongo.read_preferences: %q is not one of %q", op, operation.Reads))
+			continue
+		}
+		rp, err := readPreference(mode)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mongo.read_preferences: %s: %w", op, err))
+			continue
+		}
+		get(op).SetReadPreference(rp)
+	}
+	allOps := append(slices.Clone(operation.Reads), operation.Writes...)
+	for _, op := range slices.Sorted(maps.Keys(c.ReadConcerns)) {
+		level := c.ReadConcerns[op]
+		if !slices.Contains(allOps, op) {
+			errs = append(errs, fmt.Errorf("mongo.read_concerns: %q is not one of %q", op, allOps))
+			continue
+		}
+		rc, err := readConcern(level)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mongo.read_concerns: %s: %w", op, err))
+			continue
+		}
+		get(op).SetReadConcern(rc)
+	}
+	for _, op := range slices.Sorted(maps.Keys(c.WriteConcerns)) {
+		w := c.WriteConcerns[op]
+		if !slices.Contains(operation.Writes, op) {
+			errs = append(errs, fmt.Errorf("mongo.write_concerns: %q is not one of %q", op, operation.Writes))
+			continue
+		}
+		wc, err := c.writeConcern(w)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mongo.write_concerns: %s: %w", op, err))
+			continue
+		}
+		get(op).SetWriteConcern(wc)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return ops, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Change is a setting that differs between two configurations. Old and New
+// are printable, with secrets redacted.
+type Change struct {
+	Path string
+	Old  string
+	New  string
+	// Reload tells whether the change can be applied without a restart.
+	Reload bool
+}
+
+// Diff lists the settings that differ from a to b in declaration order.
+func Diff(a, b *Config) []Change {
+	before, after := settingsOf(a), settingsOf(b)
+	printedBefore, printedAfter := settingsOf(a.Redacted()), settingsOf(b.Redacted())
+
+	var changes []Change
+	for i, s := range before {
+		if equal(s.value, after[i].value) {
+			continue
+		}
+		changes = append(changes, Change{
+			Path:   s.path,
+			Old:    fmt.Sprint(printedBefore[i].value),
+			New:    fmt.Sprint(printedAfter[i].value),
+			Reload: s.reload,
+		})
+	}
+	return changes
+}
+
+// equal compares like reflect.DeepEqual, except that nil and empty maps
+// and lists are the same setting.
+func equal(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Map, reflect.Slice:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
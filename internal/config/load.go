@@ -0,0 +1,220 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CommandLine holds what Load found on the command line besides settings.
+type CommandLine struct {
+	// File is the configuration file that was read, if any.
+	File string
+	// PrintConfig asks to print the effective configuration and exit.
+	PrintConfig bool
+	// Args are the arguments left after the flags, e.g. a command.
+	Args []string
+}
+
+// setting is one leaf of Config.
+type setting struct {
+	path   string
+	env    string
+	reload bool
+	value  reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the configuration from, in increasing precedence, the
+// defaults, the configuration file, the environment and the command line
+// flags in args. The file is YAML or JSON and is named by --config or
+// CONFIG_FILE. Every problem found along the way, including failed
+// validation, is reported in one joined error. flag.ErrHelp is returned
+// as is when args ask for help.
+func Load(args []string) (*Config, *CommandLine, error) {
+	cfg := Default()
+	settings := settingsOf(cfg)
+
+	cmdLine := &CommandLine{File: os.Getenv("CONFIG_FILE")}
+	flags := flag.NewFlagSet("events-service", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&cmdLine.File, "config", cmdLine.File, "YAML or JSON configuration file (CONFIG_FILE)")
+	flags.BoolVar(&cmdLine.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+
+	// Flags are applied last, so their values are only collected here.
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	for _, s := range settings {
+		collect := func(val string) error {
+			flagValues = append(flagValues, flagValue{s, val})
+			return nil
+		}
+		usage := "same as " + s.env
+		if s.value.Kind() == reflect.Bool {
+			flags.BoolFunc(s.path, usage, collect)
+		} else {
+			flags.Func(s.path, usage, collect)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			flags.SetOutput(os.Stderr)
+			flags.PrintDefaults()
+		}
+		return nil, nil, err
+	}
+	cmdLine.Args = flags.Args()
+
+	var errs []error
+	if cmdLine.File != "" {
+		if err := loadFile(cfg, cmdLine.File); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %w", cmdLine.File, err))
+		}
+	}
+	for _, s := range settings {
+		if val, ok := os.LookupEnv(s.env); ok {
+			if err := set(s.value, val); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, f := range flagValues {
+		if err := set(f.setting.value, f.value); err != nil {
+			errs = append(errs, fmt.Errorf("--%s: %w", f.setting.path, err))
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	cfg.Mongo.Options = cfg.Mongo.clientOptions()
+	cfg.Mongo.Operations, _ = cfg.Mongo.operationOptions()
+	return cfg, cmdLine, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// JSON is a subset of YAML, so one decoder reads both.
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// settingsOf lists the leaves of cfg in declaration order.
+func settingsOf(cfg *Config) []setting {
+	var settings []setting
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := yamlName(field)
+			if name == "-" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			value := v.Field(i)
+			if field.Type.Kind() == reflect.Struct {
+				walk(value, name)
+				continue
+			}
+			settings = append(settings, setting{
+				path:   name,
+				env:    field.Tag.Get("env"),
+				reload: field.Tag.Get("reload") == "true",
+				value:  value,
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return settings
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return name
+}
+
+// set parses val into v according to its type.
+func set(v reflect.Value, val string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(val))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var list []string
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
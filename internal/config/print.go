@@ -0,0 +1,84 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+// urlPassword matches the password in the user info of a connection URL.
+var urlPassword = regexp.MustCompile(`(://[^:/@]*:)[^@/]*@`)
+
+// Redacted returns a copy of c with passwords and API key hashes replaced,
+// safe to print or log.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.Mongo.URI = redactURL(c.Mongo.URI)
+	out.Postgres.URL = redactURL(c.Postgres.URL)
+	out.Auth.APIKeys = slices.Clone(c.Auth.APIKeys)
+	for i := range out.Auth.APIKeys {
+		out.Auth.APIKeys[i].Hash = redacted
+	}
+	return &out
+}
+
+func redactURL(url string) string {
+	return urlPassword.ReplaceAllString(url, "${1}"+redacted+"@")
+}
+
+// Print writes c with secrets redacted in the format of the configuration
+// file, so that the output can be used as one.
+func (c *Config) Print(w io.Writer) error {
+	node, err := toNode(reflect.ValueOf(c.Redacted()).Elem())
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// toNode encodes v like yaml.Marshal does, except that durations are
+// written as strings such as "5s" which Load reads back.
+func toNode(v reflect.Value) (*yaml.Node, error) {
+	if v.Type() == durationType {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v.Interface().(time.Duration).String()}, nil
+	}
+	if v.Kind() != reflect.Struct {
+		// Empty maps and lists are written as {} and [] rather than null.
+		switch {
+		case v.Kind() == reflect.Map && v.IsNil():
+			v = reflect.MakeMap(v.Type())
+		case v.Kind() == reflect.Slice && v.IsNil():
+			v = reflect.MakeSlice(v.Type(), 0, 0)
+		}
+		node := &yaml.Node{}
+		if err := node.Encode(v.Interface()); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < v.NumField(); i++ {
+		name := yamlName(v.Type().Field(i))
+		if name == "-" {
+			continue
+		}
+		val, err := toNode(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, val)
+	}
+	return node, nil
+}
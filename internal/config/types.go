@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// APIKeys is a list of API keys. In the configuration file it is a list of
+// objects; in the environment and on the command line it is the compact
+// form "name:sha256hex:scope1,scope2", separated by ";", where a name of
+// the form "tenant/name" binds the key to that tenant.
+type APIKeys []APIKeyConfig
+
+func (k *APIKeys) UnmarshalText(text []byte) error {
+	var keys APIKeys
+	for i, entry := range strings.Split(string(text), ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			// The entry itself may hold a secret, so it is only numbered.
+			return fmt.Errorf("malformed API key #%d, want name:hash:scopes", i+1)
+		}
+
+		key := APIKeyConfig{
+			Name:   parts[0],
+			Hash:   strings.ToLower(parts[1]),
+			Scopes: strings.Split(parts[2], ","),
+		}
+		if tenant, name, ok := strings.Cut(parts[0], "/"); ok {
+			key.Tenant, key.Name = tenant, name
+		}
+		keys = append(keys, key)
+	}
+	*k = keys
+	return nil
+}
+
+// StringMap maps names to values. In the configuration file it is an
+// object; in the environment and on the command line it is the compact
+// form "name=value", separated by ";".
+type StringMap map[string]string
+
+func (m *StringMap) UnmarshalText(text []byte) error {
+	values := make(StringMap)
+	for _, entry := range strings.Split(string(text), ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, val, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("malformed entry %q, want name=value", entry)
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	}
+	*m = values
+	return nil
+}
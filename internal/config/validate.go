@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/ratelimit"
+	"github.com/godev/events-service/internal/retention"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// knownScopes are the scopes the API checks; granting others has no effect
+// and is most likely a typo.
+var knownScopes = []string{auth.ScopeEventsRead, auth.ScopeEventsWrite, auth.ScopeAuditRead, auth.ScopeAdmin}
+
+// Validate reports every invalid setting of c in one joined error.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, val string, allowed ...string) {
+		check(slices.Contains(allowed, val), "%s: %q is not one of %q", name, val, allowed)
+	}
+	nonNegative := func(name string, d time.Duration) {
+		check(d >= 0, "%s: must not be negative", name)
+	}
+	port := func(name string, p int) {
+		check(p > 0 && p < 65536, "%s: %d is not a valid port", name, p)
+	}
+	scopes := func(name string, granted []string) {
+		for _, scope := range granted {
+			oneOf(name, scope, knownScopes...)
+		}
+	}
+
+	oneOf("storage.backend", c.Storage.Backend, "mongo", "sqlite", "postgres")
+	oneOf("storage.mode", c.Storage.Mode, "state", "eventsourced")
+	mongo := c.Storage.Backend == "mongo"
+	check(mongo || c.Storage.Mode == "state", "storage.mode: eventsourced needs the mongo storage backend")
+
+	if mongo {
+		check(c.Mongo.Database != "", "mongo.database: must be set")
+		if err := c.Mongo.clientOptions().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("mongo: %w", err))
+		}
+		check(c.Mongo.MaxPoolSize == 0 || c.Mongo.MinPoolSize <= c.Mongo.MaxPoolSize,
+			"mongo.min_pool_size: must not exceed max_pool_size")
+		if _, err := readPreference(c.Mongo.ReadPreference); err != nil {
+			errs = append(errs, fmt.Errorf("mongo.read_preference: %w", err))
+		}
+		if _, err := readConcern(c.Mongo.ReadConcern); err != nil {
+			errs = append(errs, fmt.Errorf("mongo.read_concern: %w", err))
+		}
+		if _, err := c.Mongo.writeConcern(c.Mongo.WriteConcern); err != nil {
+			errs = append(errs, fmt.Errorf("mongo.write_concern: %w", err))
+		}
+		if _, err := c.Mongo.operationOptions(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	nonNegative("mongo.max_conn_idle_time", c.Mongo.MaxConnIdleTime)
+	nonNegative("mongo.connect_timeout", c.Mongo.ConnectTimeout)
+	nonNegative("mongo.server_selection_timeout", c.Mongo.ServerSelectionTimeout)
+	nonNegative("mongo.heartbeat_interval", c.Mongo.HeartbeatInterval)
+	nonNegative("mongo.timeout", c.Mongo.Timeout)
+	nonNegative("mongo.write_timeout", c.Mongo.WriteTimeout)
+	nonNegative("mongo.startup_timeout", c.Mongo.StartupTimeout)
+	check(c.Mongo.RetryInitialInterval > 0, "mongo.retry_initial_interval: must be positive")
+	check(c.Mongo.RetryMaxInterval >= c.Mongo.RetryInitialInterval,
+		"mongo.retry_max_interval: must not be less than retry_initial_interval")
+	check(c.Storage.Backend != "sqlite" || c.SQLite.Path != "", "sqlite.path: must be set")
+	check(c.Storage.Backend != "postgres" || c.Postgres.URL != "", "postgres.url: must be set")
+
+	port("server.port", c.Server.Port)
+	port("server.grpc_port", c.Server.GRPCPort)
+	port("server.admin_port", c.Server.AdminPort)
+	nonNegative("server.shutdown_delay", c.Server.ShutdownDelay)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+	check(c.Server.CloseTimeout > 0, "server.close_timeout: must be positive")
+	check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes: must be positive")
+	check(c.Server.MaxBodyBytes > 0, "server.max_body_bytes: must be positive")
+	check(c.Server.IdempotencyTTL > 0, "server.idempotency_ttl: must be positive")
+	check(c.Server.IdempotencyMaxKeys > 0, "server.idempotency_max_keys: must be positive")
+	nonNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	check((c.Server.TLS.CertFile == "") == (c.Server.TLS.KeyFile == ""),
+		"server.tls: cert_file and key_file must be set together")
+	check(c.Server.TLS.ClientCAFile == "" || c.Server.TLS.CertFile != "",
+		"server.tls.client_ca_file: mutual TLS needs cert_file and key_file")
+	oneOf("server.tls.client_auth", c.Server.TLS.ClientAuth, "require", "optional")
+	oneOf("server.tls.min_version", c.Server.TLS.MinVersion, "1.2", "1.3")
+	for _, proxy := range c.Server.TrustedProxies {
+		_, addrErr := netip.ParseAddr(proxy)
+		_, prefixErr := netip.ParsePrefix(proxy)
+		check(addrErr == nil || prefixErr == nil, "server.trusted_proxies: %q is neither an address nor a CIDR range", proxy)
+	}
+
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	oneOf("log.encoding", c.Log.Encoding, "json", "console")
+	check(c.Log.SamplingInitial >= 0 && c.Log.SamplingThereafter >= 0, "log: sampling must not be negative")
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, "none", "otlp", "stdout")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio: must be between 0 and 1")
+
+	if c.Auth.Enabled {
+		check(len(c.Auth.APIKeys) > 0 || c.Auth.APIKeysCollection != "" || c.Auth.JWKSFile != "" || c.Server.TLS.ClientCAFile != "",
+			"auth: authentication is enabled but no API keys, JWKS file or client CA are configured")
+	}
+	scopes("auth.anonymous_scopes", c.Auth.AnonymousScopes)
+	scopes("auth.client_cert_scopes", c.Auth.ClientCertScopes)
+	for _, key := range c.Auth.APIKeys {
+		check(key.Name != "", "auth.api_keys: key without a name")
+		scopes("auth.api_keys: key "+key.Name, key.Scopes)
+		if _, err := hex.DecodeString(key.Hash); err != nil || len(key.Hash) != 64 {
+			errs = append(errs, fmt.Errorf("auth.api_keys: key %s: hash must be a hex-encoded SHA-256", key.Name))
+		}
+		if key.Tenant != "" {
+			if err := tenant.Validate(key.Tenant); err != nil {
+				errs = append(errs, fmt.Errorf("auth.api_keys: key %s: %w", key.Name, err))
+			}
+		}
+	}
+	check(mongo || c.Auth.APIKeysCollection == "", "auth.api_keys_collection: needs the mongo storage backend")
+
+	oneOf("rate_limit.backend", c.RateLimit.Backend, "memory", "mongo")
+	if c.RateLimit.Enabled {
+		check(mongo || c.RateLimit.Backend != "mongo", "rate_limit.backend: mongo needs the mongo storage backend")
+	}
+	if c.RateLimit.Default != "" {
+		if _, err := ratelimit.ParseLimit(c.RateLimit.Default); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit.default: %w", err))
+		}
+	}
+	for _, route := range slices.Sorted(maps.Keys(c.RateLimit.Routes)) {
+		if _, err := ratelimit.ParseLimit(c.RateLimit.Routes[route]); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limit.routes: %s: %w", route, err))
+		}
+	}
+
+	if c.Retention.Default != "" {
+		if _, err := retention.ParseDuration(c.Retention.Default); err != nil {
+			errs = append(errs, fmt.Errorf("retention.default: %w", err))
+		}
+	}
+	for _, eventType := range slices.Sorted(maps.Keys(c.Retention.Types)) {
+		if _, err := retention.ParseDuration(c.Retention.Types[eventType]); err != nil {
+			errs = append(errs, fmt.Errorf("retention.types: %s: %w", eventType, err))
+		}
+	}
+	check(c.Retention.Interval > 0, "retention.interval: must be positive")
+	check(c.Retention.BatchSize > 0, "retention.batch_size: must be positive")
+	oneOf("retention.archive", c.Retention.Archive, "none", "collection", "file")
+	check(c.Retention.Archive != "file" || c.Retention.ArchiveDir != "", "retention.archive_dir: must be set for the file archive")
+
+	return errors.Join(errs...)
+}
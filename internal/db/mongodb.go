@@ -5,8 +5,10 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.uber.org/zap"
 
+	"github.com/godev/events-service/internal/backoff"
 	"github.com/godev/events-service/internal/config"
 )
 
@@ -16,6 +18,11 @@ type MongoDB struct {
 	logger   *zap.Logger
 }
 
+// NewMongoDB connects to the deployment and waits up to cfg.StartupTimeout
+// for it to answer, retrying with backoff. A deployment that does not
+// answer in time fails the startup only when cfg.DegradedStart is off;
+// otherwise the client is returned anyway and keeps reconnecting, so that
+// the server can come up degraded.
 func NewMongoDB(logger *zap.Logger, cfg *config.MongoConfig) (*MongoDB, error) {
 	client, err := mongo.Connect(context.Background(), cfg.Options)
 	if err != nil {
@@ -25,18 +32,32 @@ func NewMongoDB(logger *zap.Logger, cfg *config.MongoConfig) (*MongoDB, error) {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.StartupTimeout)
 	defer cancel()
-	if err := client.Ping(ctx, nil); err != nil {
+	retry := backoff.Backoff{Initial: cfg.RetryInitialInterval, Max: cfg.RetryMaxInterval}
+	err = backoff.Retry(ctx, retry, func(ctx context.Context) error {
+		return client.Ping(ctx, nil)
+	}, func(attempt int, err error, wait time.Duration) {
+		logger.Warn("MongoDB is not reachable yet",
+			zap.Error(err),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", wait))
+	})
+	if err != nil {
 		logger.Error("Failed to ping MongoDB",
 			zap.Error(err),
+			zap.String("uri", cfg.URI),
+			zap.Duration("startup_timeout", cfg.StartupTimeout))
+		if !cfg.DegradedStart {
+			_ = client.Disconnect(context.Background())
+			return nil, err
+		}
+		logger.Warn("Starting without MongoDB, requests that need it fail with 503 until it is reachable")
+	} else {
+		logger.Info("Successfully connected to MongoDB",
 			zap.String("uri", cfg.URI))
-		return nil, err
 	}
 
-	logger.Info("Successfully connected to MongoDB",
-		zap.String("uri", cfg.URI))
-
 	return &MongoDB{
 		client:   client,
 		database: client.Database(cfg.Database),
@@ -48,12 +69,23 @@ func (m *MongoDB) GetCollection(name string) *mongo.Collection {
 	return m.database.Collection(name)
 }
 
-func (m *MongoDB) Close() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Close waits for operations in progress until ctx is done and then
+// disconnects.
+func (m *MongoDB) Close(ctx context.Context) error {
 	return m.client.Disconnect(ctx)
 }
 
 func (m *MongoDB) GetDatabase() *mongo.Database {
 	return m.database
 }
+
+// Ping checks that any member of the deployment answers.
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, readpref.Nearest())
+}
+
+// PingPrimary checks that the replica set has a reachable primary, which
+// every write and transaction needs.
+func (m *MongoDB) PingPrimary(ctx context.Context) error {
+	return m.client.Ping(ctx, readpref.Primary())
+}
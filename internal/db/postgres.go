@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/config"
+)
+
+type Postgres struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+// NewPostgres connects to cfg.URL. Pool settings such as pool_max_conns are
+// read from the URL.
+func NewPostgres(logger *zap.Logger, cfg *config.PostgresConfig) (*Postgres, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		logger.Error("Invalid PostgreSQL URL", zap.Error(err))
+		return nil, err
+	}
+	// The URL may carry a password, so only the host and database are logged.
+	fields := []zap.Field{
+		zap.String("host", poolConfig.ConnConfig.Host),
+		zap.String("database", poolConfig.ConnConfig.Database),
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		logger.Error("Failed to connect to PostgreSQL", append(fields, zap.Error(err))...)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		logger.Error("Failed to ping PostgreSQL", append(fields, zap.Error(err))...)
+		return nil, err
+	}
+
+	logger.Info("Successfully connected to PostgreSQL", fields...)
+
+	return &Postgres{pool: pool, logger: logger}, nil
+}
+
+func (p *Postgres) GetPool() *pgxpool.Pool {
+	return p.pool
+}
+
+func (p *Postgres) Ping(ctx context.Context) error {
+	return p.pool.Ping(ctx)
+}
+
+func (p *Postgres) Close() {
+	p.pool.Close()
+}
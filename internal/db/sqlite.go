@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+
+	"go.uber.org/zap"
+	// Registers the pure-Go "sqlite" driver.
+	_ "modernc.org/sqlite"
+
+	"github.com/godev/events-service/internal/config"
+)
+
+type SQLite struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewSQLite opens the database file at cfg.Path. Writers wait for each other
+// instead of failing with SQLITE_BUSY, and transactions take the write lock
+// when they begin, so a read-then-write transaction cannot be overtaken.
+func NewSQLite(logger *zap.Logger, cfg *config.SQLiteConfig) (*SQLite, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+cfg.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer; one connection keeps writers from queueing
+	// on the file lock and lets an in-memory database be shared.
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(context.Background()); err != nil {
+		_ = db.Close()
+		logger.Error("Failed to open SQLite database",
+			zap.Error(err),
+			zap.String("path", cfg.Path))
+		return nil, err
+	}
+
+	logger.Info("Successfully opened SQLite database",
+		zap.String("path", cfg.Path))
+
+	return &SQLite{db: db, logger: logger}, nil
+}
+
+func (s *SQLite) GetDB() *sql.DB {
+	return s.db
+}
+
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/service"
+)
+
+type AuditHandler struct {
+	base
+	service service.IAuditService
+}
+
+func NewAuditHandler(service service.IAuditService) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+	}
+}
+
+// ListAudit returns audit entries, newest first. They can be filtered by
+// actor, action, eventId, type and an RFC 3339 from/to range. Pages hold
+// at most 100 entries; a larger limit is lowered to that.
+func (h *AuditHandler) ListAudit(c *gin.Context) {
+	span := h.startSpan(c, "AuditHandler.ListAudit")
+	defer h.endSpan(c, span)
+
+	filter := model.AuditFilter{
+		Actor:     c.Query("actor"),
+		Action:    model.AuditAction(c.Query("action")),
+		EventType: c.Query("type"),
+	}
+
+	var err error
+	if filter.Offset, err = strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64); err != nil || filter.Offset < 0 {
+		h.errorJSON(c, http.StatusBadRequest, "invalid offset parameter")
+		return
+	}
+	if filter.Limit, err = strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64); err != nil || filter.Limit < 1 {
+		h.errorJSON(c, http.StatusBadRequest, "invalid limit parameter")
+		return
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if id := c.Query("eventId"); id != "" {
+		if filter.EventID, err = primitive.ObjectIDFromHex(id); err != nil {
+			h.errorJSON(c, http.StatusBadRequest, service.ErrInvalidEventID.Error())
+			return
+		}
+	}
+	if filter.From, err = parseTime(c.Query("from")); err != nil {
+		h.errorJSON(c, http.StatusBadRequest, "invalid from parameter")
+		return
+	}
+	if filter.To, err = parseTime(c.Query("to")); err != nil {
+		h.errorJSON(c, http.StatusBadRequest, "invalid to parameter")
+		return
+	}
+
+	entries, err := h.service.ListAudit(c.Request.Context(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAuditAction),
+			errors.Is(err, service.ErrInvalidEventType),
+			errors.Is(err, service.ErrInvalidTimeRange):
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
+		default:
+			h.serverError(c, err, "Failed to list audit entries")
+		}
+		return
+	}
+
+	if entries == nil {
+		entries = []model.AuditEntry{}
+	}
+	c.JSON(http.StatusOK, model.AuditResponse{Entries: entries})
+}
+
+// parseTime reads an optional RFC 3339 timestamp.
+func parseTime(val string) (time.Time, error) {
+	if val == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/service"
+)
+
+// recordingAuditService remembers the filter it was asked for.
+type recordingAuditService struct {
+	filter *model.AuditFilter
+	err    error
+}
+
+func (s *recordingAuditService) ListAudit(_ context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	s.filter = &filter
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []model.AuditEntry{{Actor: "ci", Action: model.AuditActionStart}}, nil
+}
+
+func TestListAudit(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		query      string
+		serviceErr error
+		wantStatus int
+		wantFilter *model.AuditFilter
+	}{
+		{
+			name:       "defaults",
+			wantStatus: http.StatusOK,
+			wantFilter: &model.AuditFilter{Limit: 100},
+		},
+		{
+			name:       "filters",
+			query:      "?actor=ci&action=start&type=deploy&from=2026-01-01T00:00:00Z&offset=20&limit=10",
+			wantStatus: http.StatusOK,
+			wantFilter: &model.AuditFilter{Actor: "ci", Action: model.AuditActionStart, EventType: "deploy", From: from, Offset: 20, Limit: 10},
+		},
+		{
+			name:       "limit is lowered to a full page",
+			query:      "?limit=1000",
+			wantStatus: http.StatusOK,
+			wantFilter: &model.AuditFilter{Limit: 100},
+		},
+		{name: "zero limit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "negative limit", query: "?limit=-5", wantStatus: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", wantStatus: http.StatusBadRequest},
+		{name: "malformed limit", query: "?limit=ten", wantStatus: http.StatusBadRequest},
+		{name: "malformed event id", query: "?eventId=42", wantStatus: http.StatusBadRequest},
+		{name: "malformed time", query: "?from=yesterday", wantStatus: http.StatusBadRequest},
+		{
+			name:       "rejected filter",
+			query:      "?action=delete",
+			serviceErr: service.ErrInvalidAuditAction,
+			wantStatus: http.StatusBadRequest,
+			wantFilter: &model.AuditFilter{Action: "delete", Limit: 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &recordingAuditService{err: tt.serviceErr}
+			h := NewAuditHandler(svc)
+
+			w := serve(t, http.MethodGet, "/v1/audit"+tt.query, "", func(r *gin.Engine) {
+				r.GET("/v1/audit", h.ListAudit)
+			})
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantFilter, svc.filter)
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp model.AuditResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Len(t, resp.Entries, 1)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/apierror"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/reqctx"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+// base holds the helpers every handler shares.
+type base struct{}
+
+// logger returns the request-scoped logger, which carries the request ID.
+func (base) logger(c *gin.Context) *zap.Logger {
+	return logger.FromContext(c.Request.Context())
+}
+
+func (base) errorJSON(c *gin.Context, status int, message string) {
+	c.JSON(status, apierror.Response(c, message))
+}
+
+// serverError answers a request that failed for err. Storage that cannot be
+// reached gets 503 with Retry-After, since the request may succeed later;
+// anything else is logged and gets 500.
+func (b base) serverError(c *gin.Context, err error, message string, fields ...zap.Field) {
+	fields = append(fields, zap.Error(err))
+	if errors.Is(err, repository.ErrUnavailable) {
+		b.logger(c).Warn(message, fields...)
+		apierror.RetryAfter(c, repository.RetryAfter)
+		b.errorJSON(c, http.StatusServiceUnavailable, message+": storage is unavailable")
+		return
+	}
+	b.logger(c).Error(message, fields...)
+	b.errorJSON(c, http.StatusInternalServerError, message)
+}
+
+// bindJSON decodes the request body into v. When it cannot, it answers 413
+// for bodies over the size limit and 400 otherwise, and returns false.
+func (b base) bindJSON(c *gin.Context, v any) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.errorJSON(c, http.StatusRequestEntityTooLarge, "Request body too large")
+		return false
+	}
+	b.logger(c).Error("Invalid request body", zap.Error(err))
+	b.errorJSON(c, http.StatusBadRequest, "Invalid request body")
+	return false
+}
+
+// startSpan starts a handler span as a child of the request span and makes
+// it the parent of everything the service does for this request.
+func (base) startSpan(c *gin.Context, name string) trace.Span {
+	ctx, span := tracing.Start(c.Request.Context(), name,
+		attribute.String("request.id", reqctx.RequestID(c.Request.Context())))
+	c.Request = c.Request.WithContext(ctx)
+	return span
+}
+
+func (base) endSpan(c *gin.Context, span trace.Span) {
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
@@ -8,36 +8,35 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
-	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/apierror"
 	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
 	"github.com/godev/events-service/internal/service"
 )
 
 type EventHandler struct {
+	base
 	service service.IEventService
-	log     *zap.Logger
 }
 
 func NewEventHandler(service service.IEventService) *EventHandler {
 	return &EventHandler{
 		service: service,
-		log:     logger.Get(),
 	}
 }
 
 func (h *EventHandler) ListEvents(c *gin.Context) {
+	span := h.startSpan(c, "EventHandler.ListEvents")
+	defer h.endSpan(c, span)
+
 	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Message: "invalid offset parameter",
-		})
+		h.errorJSON(c, http.StatusBadRequest, "invalid offset parameter")
 		return
 	}
 	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Message: "invalid limit parameter",
-		})
+		h.errorJSON(c, http.StatusBadRequest, "invalid limit parameter")
 		return
 	}
 	if limit > 100 {
@@ -46,7 +45,7 @@ func (h *EventHandler) ListEvents(c *gin.Context) {
 
 	eventType := c.Query("type")
 
-	h.log.Info("Listing events",
+	h.logger(c).Info("Listing events",
 		zap.String("type", eventType),
 		zap.Int64("offset", offset),
 		zap.Int64("limit", limit))
@@ -55,12 +54,11 @@ func (h *EventHandler) ListEvents(c *gin.Context) {
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidEventType):
-			c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
 		case errors.Is(err, service.ErrInvalidLimit):
-			c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
 		default:
-			h.log.Error("Failed to list events", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to list events"})
+			h.serverError(c, err, "Failed to list events")
 		}
 		return
 	}
@@ -68,29 +66,78 @@ func (h *EventHandler) ListEvents(c *gin.Context) {
 	c.JSON(http.StatusOK, model.EventsResponse{Events: events})
 }
 
+func (h *EventHandler) GetEvent(c *gin.Context) {
+	span := h.startSpan(c, "EventHandler.GetEvent")
+	defer h.endSpan(c, span)
+
+	id := c.Param("id")
+
+	event, err := h.service.GetEvent(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidEventID):
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrNoSuchEvent):
+			h.errorJSON(c, http.StatusNotFound, err.Error())
+		default:
+			h.serverError(c, err, "Failed to get event", zap.String("id", id))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, event)
+}
+
+// GetEventHistory returns every transition of the event, oldest first,
+// including updates that were rejected by optimistic locking.
+func (h *EventHandler) GetEventHistory(c *gin.Context) {
+	span := h.startSpan(c, "EventHandler.GetEventHistory")
+	defer h.endSpan(c, span)
+
+	id := c.Param("id")
+
+	history, err := h.service.GetEventHistory(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidEventID):
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrNoSuchEvent):
+			h.errorJSON(c, http.StatusNotFound, err.Error())
+		default:
+			h.serverError(c, err, "Failed to get event history", zap.String("id", id))
+		}
+		return
+	}
+
+	if history == nil {
+		history = []model.HistoryEntry{}
+	}
+	c.JSON(http.StatusOK, model.HistoryResponse{History: history})
+}
+
 func (h *EventHandler) StartEvent(c *gin.Context) {
+	span := h.startSpan(c, "EventHandler.StartEvent")
+	defer h.endSpan(c, span)
+
 	var req model.EventRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.log.Error("Invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Invalid request body"})
+	if !h.bindJSON(c, &req) {
 		return
 	}
 
 	if req.Type == "" {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Event type is required"})
+		h.errorJSON(c, http.StatusBadRequest, "Event type is required")
 		return
 	}
 
-	h.log.Info("Starting event", zap.String("type", req.Type))
+	h.logger(c).Info("Starting event", zap.String("type", req.Type))
 
 	err := h.service.StartEvent(c.Request.Context(), req.Type)
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidEventType):
-			c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
 		default:
-			h.log.Error("Failed to start event", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to start event"})
+			h.serverError(c, err, "Failed to start event")
 		}
 		return
 	}
@@ -99,34 +146,146 @@ func (h *EventHandler) StartEvent(c *gin.Context) {
 }
 
 func (h *EventHandler) FinishEvent(c *gin.Context) {
+	span := h.startSpan(c, "EventHandler.FinishEvent")
+	defer h.endSpan(c, span)
+
 	var req model.EventRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		h.log.Error("Invalid request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Invalid request body"})
+	if !h.bindJSON(c, &req) {
 		return
 	}
 
 	if req.Type == "" {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: "Event type is required"})
+		h.errorJSON(c, http.StatusBadRequest, "Event type is required")
 		return
 	}
 
-	h.log.Info("Finishing event", zap.String("type", req.Type))
+	h.logger(c).Info("Finishing event", zap.String("type", req.Type))
 
 	err := h.service.FinishEvent(c.Request.Context(), req.Type)
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidEventType):
-			c.JSON(http.StatusBadRequest, model.ErrorResponse{Message: err.Error()})
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
 		case errors.Is(err, service.ErrEventNotFound):
-			h.log.Warn("No unfinished event found", zap.String("type", req.Type))
-			c.JSON(http.StatusNotFound, model.ErrorResponse{Message: err.Error()})
+			h.logger(c).Warn("No unfinished event found", zap.String("type", req.Type))
+			h.errorJSON(c, http.StatusNotFound, err.Error())
+		default:
+			h.serverError(c, err, "Failed to finish event")
+		}
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *EventHandler) BatchEvents(c *gin.Context) {
+	span := h.startSpan(c, "EventHandler.BatchEvents")
+	defer h.endSpan(c, span)
+
+	var req model.BatchRequest
+	if !h.bindJSON(c, &req) {
+		return
+	}
+
+	h.logger(c).Info("Executing batch",
+		zap.Int("operations", len(req.Operations)),
+		zap.Bool("atomic", req.Atomic))
+
+	errs, committed, err := h.service.ExecuteBatch(c.Request.Context(), req.Operations, req.Atomic)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptyBatch), errors.Is(err, service.ErrBatchTooLarge):
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
 		default:
-			h.log.Error("Failed to finish event", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Message: "Failed to finish event"})
+			h.serverError(c, err, "Failed to execute batch")
 		}
 		return
 	}
 
+	resp := model.BatchResponse{Committed: committed, Results: make([]model.BatchResult, len(req.Operations))}
+	for i, op := range req.Operations {
+		result := model.BatchResult{Op: op.Op, Type: op.Type, Status: http.StatusOK}
+		if errs[i] != nil {
+			result.Status, result.Message = h.batchErrorStatus(c, op, errs[i])
+		}
+		resp.Results[i] = result
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *EventHandler) batchErrorStatus(c *gin.Context, op model.BatchOperation, err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidEventType), errors.Is(err, service.ErrInvalidOperation):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrEventNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, service.ErrBatchAborted):
+		return http.StatusFailedDependency, err.Error()
+	case errors.Is(err, repository.ErrTransactionAborted):
+		return http.StatusConflict, "Failed to " + string(op.Op) + " event: a concurrent request changed it, retry the batch"
+	case errors.Is(err, repository.ErrUnavailable):
+		apierror.RetryAfter(c, repository.RetryAfter)
+		return http.StatusServiceUnavailable, "Failed to " + string(op.Op) + " event: storage is unavailable"
+	default:
+		h.logger(c).Error("Batch operation failed",
+			zap.String("op", string(op.Op)),
+			zap.String("type", op.Type),
+			zap.Error(err))
+		return http.StatusInternalServerError, "Failed to " + string(op.Op) + " event"
+	}
+}
+
+func (h *EventHandler) GetStats(c *gin.Context) {
+	span := h.startSpan(c, "EventHandler.GetStats")
+	defer h.endSpan(c, span)
+
+	eventType := c.Query("type")
+
+	stats, err := h.service.GetStats(c.Request.Context(), eventType)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidEventType):
+			h.errorJSON(c, http.StatusBadRequest, err.Error())
+		default:
+			h.serverError(c, err, "Failed to get stats")
+		}
+		return
+	}
+
+	if stats == nil {
+		stats = []model.EventStats{}
+	}
+	c.JSON(http.StatusOK, model.StatsResponse{Stats: stats})
+}
+
+// WatchEvents streams started and finished events as server-sent events.
+// Each message has the "event" name and a JSON encoded model.Event as data;
+// a failure after the stream has started is sent as an "error" message.
+func (h *EventHandler) WatchEvents(c *gin.Context) {
+	eventType := c.Query("type")
+	if err := service.ValidateEventTypeFilter(eventType); err != nil {
+		h.errorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.logger(c).Info("Watching events", zap.String("type", eventType))
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
 	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	err := h.service.WatchEvents(ctx, eventType, func(event model.Event) error {
+		c.SSEvent("event", event)
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		h.logger(c).Error("Failed to watch events", zap.Error(err))
+		c.SSEvent("error", apierror.Response(c, "Failed to watch events"))
+		c.Writer.Flush()
+	}
 }
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/service"
+)
+
+// stubEventService answers with whatever its fields return; methods whose
+// field is nil panic, so a test notices calls it did not expect.
+type stubEventService struct {
+	service.IEventService
+	executeBatch func(ops []model.BatchOperation, atomic bool) ([]error, bool, error)
+	getStats     func(eventType string) ([]model.EventStats, error)
+	watchEvents  func(ctx context.Context, eventType string, fn func(model.Event) error) error
+}
+
+func (s *stubEventService) ExecuteBatch(_ context.Context, ops []model.BatchOperation, atomic bool) ([]error, bool, error) {
+	return s.executeBatch(ops, atomic)
+}
+
+func (s *stubEventService) GetStats(_ context.Context, eventType string) ([]model.EventStats, error) {
+	return s.getStats(eventType)
+}
+
+func (s *stubEventService) WatchEvents(ctx context.Context, eventType string, fn func(model.Event) error) error {
+	return s.watchEvents(ctx, eventType, fn)
+}
+
+func serve(t *testing.T, method, path, body string, register func(r *gin.Engine)) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	register(router)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBatchEvents(t *testing.T) {
+	ops := `{"atomic": %s, "operations": [{"op": "start", "type": "build"}, {"op": "finish", "type": "deploy"}]}`
+
+	tests := []struct {
+		name      string
+		atomic    string
+		errs      []error
+		committed bool
+		statuses  []int
+	}{
+		{name: "all written", atomic: "false", errs: []error{nil, nil},
+			committed: true, statuses: []int{http.StatusOK, http.StatusOK}},
+		{name: "some written", atomic: "false", errs: []error{nil, service.ErrEventNotFound},
+			committed: true, statuses: []int{http.StatusOK, http.StatusNotFound}},
+		{name: "none written", atomic: "false", errs: []error{service.ErrInvalidEventType, service.ErrEventNotFound},
+			committed: false, statuses: []int{http.StatusBadRequest, http.StatusNotFound}},
+		{name: "atomic rolled back", atomic: "true", errs: []error{service.ErrBatchAborted, service.ErrEventNotFound},
+			committed: false, statuses: []int{http.StatusFailedDependency, http.StatusNotFound}},
+		{name: "nothing to write", atomic: "false", errs: []error{nil, nil},
+			committed: false, statuses: []int{http.StatusOK, http.StatusOK}},
+		{name: "atomic aborted by a concurrent start", atomic: "true", errs: []error{repository.ErrTransactionAborted, service.ErrBatchAborted},
+			committed: false, statuses: []int{http.StatusConflict, http.StatusFailedDependency}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewEventHandler(&stubEventService{
+				executeBatch: func(ops []model.BatchOperation, atomic bool) ([]error, bool, error) {
+					assert.Len(t, ops, 2)
+					assert.Equal(t, tt.atomic == "true", atomic)
+					return tt.errs, tt.committed, nil
+				},
+			})
+			w := serve(t, http.MethodPost, "/v1/batch", fmt.Sprintf(ops, tt.atomic), func(r *gin.Engine) {
+				r.POST("/v1/batch", h.BatchEvents)
+			})
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp model.BatchResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.committed, resp.Committed)
+			require.Len(t, resp.Results, len(tt.statuses))
+			for i, status := range tt.statuses {
+				assert.Equal(t, status, resp.Results[i].Status, "operation %d", i)
+			}
+		})
+	}
+}
+
+func TestBatchEventsRejectsEmptyBatch(t *testing.T) {
+	h := NewEventHandler(&stubEventService{
+		executeBatch: func([]model.BatchOperation, bool) ([]error, bool, error) {
+			return nil, false, service.ErrEmptyBatch
+		},
+	})
+	w := serve(t, http.MethodPost, "/v1/batch", `{"operations": []}`, func(r *gin.Engine) {
+		r.POST("/v1/batch", h.BatchEvents)
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetStats(t *testing.T) {
+	h := NewEventHandler(&stubEventService{
+		getStats: func(eventType string) ([]model.EventStats, error) {
+			switch eventType {
+			case "deploy":
+				return []model.EventStats{{Type: "deploy", Running: 1, Finished: 2, AvgDurationMs: 1500}}, nil
+			case "Deploy":
+				return nil, service.ErrInvalidEventType
+			default:
+				return nil, nil
+			}
+		},
+	})
+	register := func(r *gin.Engine) { r.GET("/v1/stats", h.GetStats) }
+
+	w := serve(t, http.MethodGet, "/v1/stats?type=deploy", "", register)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp model.StatsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Stats, 1)
+	assert.Equal(t, int64(2), resp.Stats[0].Finished)
+
+	w = serve(t, http.MethodGet, "/v1/stats", "", register)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"stats": []}`, w.Body.String(), "no events is an empty list, not null")
+
+	w = serve(t, http.MethodGet, "/v1/stats?type=Deploy", "", register)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWatchEvents(t *testing.T) {
+	events := []model.Event{
+		{ID: primitive.NewObjectID(), Type: "deploy", State: model.EventStateStarted, Version: 1},
+		{ID: primitive.NewObjectID(), Type: "deploy", State: model.EventStateFinished, Version: 2},
+	}
+	h := NewEventHandler(&stubEventService{
+		watchEvents: func(_ context.Context, eventType string, fn func(model.Event) error) error {
+			assert.Equal(t, "deploy", eventType)
+			for _, event := range events {
+				if err := fn(event); err != nil {
+					return err
+				}
+			}
+			return errors.New("change stream closed")
+		},
+	})
+	register := func(r *gin.Engine) { r.GET("/v1/watch", h.WatchEvents) }
+
+	w := serve(t, http.MethodGet, "/v1/watch?type=deploy", "", register)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream"))
+
+	var names []string
+	var received []model.Event
+	for _, message := range strings.Split(strings.TrimSpace(w.Body.String()), "\n\n") {
+		var name, data string
+		for _, line := range strings.Split(message, "\n") {
+			if v, ok := strings.CutPrefix(line, "event:"); ok {
+				name = v
+			}
+			if v, ok := strings.CutPrefix(line, "data:"); ok {
+				data = v
+			}
+		}
+		names = append(names, name)
+		if name == "event" {
+			var event model.Event
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			received = append(received, event)
+		}
+	}
+	assert.Equal(t, []string{"event", "event", "error"}, names, "a failure after the stream started is sent as a message")
+	require.Len(t, received, 2)
+	assert.Equal(t, events[1].ID, received[1].ID)
+
+	w = serve(t, http.MethodGet, "/v1/watch?type=Deploy", "", register)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/reqctx"
+	"github.com/godev/events-service/internal/tenant"
+	eventsv1 "github.com/godev/events-service/pkg/api/events/v1"
+)
+
+// methodScopes lists the scope every RPC requires. Methods missing from the
+// map are only available to admins.
+var methodScopes = map[string]string{
+	eventsv1.EventService_Start_FullMethodName:  auth.ScopeEventsWrite,
+	eventsv1.EventService_Finish_FullMethodName: auth.ScopeEventsWrite,
+	eventsv1.EventService_List_FullMethodName:   auth.ScopeEventsRead,
+	eventsv1.EventService_Get_FullMethodName:    auth.ScopeEventsRead,
+	eventsv1.EventService_Watch_FullMethodName:  auth.ScopeEventsRead,
+}
+
+// AuthUnaryInterceptor authenticates unary calls from the "authorization"
+// or "x-api-key" metadata, or else from the client certificate verified by
+// mutual TLS, and resolves the tenant from "x-tenant-id", like
+// the REST API does with headers.
+func AuthUnaryInterceptor(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func AuthStreamInterceptor(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authorize(ctx context.Context, authenticator *auth.Authenticator, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	creds := auth.ParseCredentials(firstValue(md, "authorization"), firstValue(md, "x-api-key"))
+	p, hasPeer := peer.FromContext(ctx)
+	if hasPeer {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			creds.ClientCert = info.State.VerifiedChains[0][0]
+		}
+	}
+
+	principal, err := authenticator.Authenticate(ctx, creds)
+	if err != nil {
+		if errors.Is(err, auth.ErrMissingCredentials) || errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "authentication required")
+		}
+		if errors.Is(err, repository.ErrUnavailable) {
+			logger.FromContext(ctx).Warn("Failed to authenticate request", zap.Error(err))
+			return nil, status.Error(codes.Unavailable, "key store is unavailable")
+		}
+		logger.FromContext(ctx).Error("Failed to authenticate request", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to authenticate request")
+	}
+
+	scope, ok := methodScopes[method]
+	if !ok {
+		scope = auth.ScopeAdmin
+	}
+	if !principal.HasScope(scope) {
+		return nil, status.Error(codes.PermissionDenied, "missing scope "+scope)
+	}
+
+	name, err := principal.ResolveTenant(firstValue(md, "x-tenant-id"))
+	if err != nil {
+		if errors.Is(err, auth.ErrTenantForbidden) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx = auth.WithPrincipal(ctx, principal)
+	if hasPeer {
+		ctx = reqctx.WithClientIP(ctx, peerIP(p.Addr))
+	}
+	return tenant.WithTenant(ctx, name), nil
+}
+
+func peerIP(addr net.Addr) string {
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		return host
+	}
+	return addr.String()
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream replaces the context of a stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/tenant"
+	eventsv1 "github.com/godev/events-service/pkg/api/events/v1"
+)
+
+func newTestAuthenticator() *auth.Authenticator {
+	return auth.NewAuthenticator(auth.NewStaticKeyStore([]auth.APIKey{
+		{Name: "reader", Hash: auth.HashKey("read-key"), Scopes: []string{auth.ScopeEventsRead}},
+		{Name: "writer", Hash: auth.HashKey("write-key"), Scopes: []string{auth.ScopeEventsWrite}, Tenant: "team-a"},
+		{Name: "admin", Hash: auth.HashKey("admin-key"), Scopes: []string{auth.ScopeAdmin}},
+	}), nil)
+}
+
+// inTenant matches contexts of requests operating in name.
+func inTenant(name string) interface{} {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		return tenant.FromContext(ctx) == name
+	})
+}
+
+func withKey(key string, pairs ...string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(append([]string{"x-api-key", key}, pairs...)...))
+}
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	svc := new(MockEventService)
+	svc.On("StartEvent", inTenant("team-a"), "deploy").Return(nil)
+	svc.On("StartEvent", inTenant("team-b"), "deploy").Return(nil)
+	svc.On("ListEvents", inTenant(tenant.Default), "", int64(0), int64(100)).Return([]model.Event{}, nil)
+	client := newTestClient(t, svc, grpc.ChainUnaryInterceptor(AuthUnaryInterceptor(newTestAuthenticator())))
+
+	start := func(ctx context.Context) error {
+		_, err := client.Start(ctx, &eventsv1.StartRequest{Type: "deploy"})
+		return err
+	}
+	list := func(ctx context.Context) error {
+		_, err := client.List(ctx, &eventsv1.ListRequest{})
+		return err
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		call       func(ctx context.Context) error
+		expectCode codes.Code
+	}{
+		{name: "no credentials", ctx: context.Background(), call: start, expectCode: codes.Unauthenticated},
+		{name: "unknown key", ctx: withKey("unknown"), call: start, expectCode: codes.Unauthenticated},
+		{name: "missing scope", ctx: withKey("read-key"), call: start, expectCode: codes.PermissionDenied},
+		{name: "bearer key", ctx: metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer read-key"), call: list, expectCode: codes.OK},
+		{name: "bound key in its tenant", ctx: withKey("write-key"), call: start, expectCode: codes.OK},
+		{name: "bound key in its tenant by name", ctx: withKey("write-key", "x-tenant-id", "team-a"), call: start, expectCode: codes.OK},
+		{name: "bound key in another tenant", ctx: withKey("write-key", "x-tenant-id", "team-b"), call: start, expectCode: codes.PermissionDenied},
+		{name: "admin in any tenant", ctx: withKey("admin-key", "x-tenant-id", "team-b"), call: start, expectCode: codes.OK},
+		{name: "admin in an invalid tenant", ctx: withKey("admin-key", "x-tenant-id", "Team B"), call: start, expectCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectCode, status.Code(tt.call(tt.ctx)))
+		})
+	}
+
+	svc.AssertNumberOfCalls(t, "StartEvent", 3)
+	svc.AssertExpectations(t)
+}
+
+func TestAuthStreamInterceptor(t *testing.T) {
+	svc := new(MockEventService)
+	svc.On("WatchEvents", inTenant("team-b"), "deploy").Return([]model.Event{{Type: "deploy", Tenant: "team-b"}}, nil)
+	client := newTestClient(t, svc, grpc.ChainStreamInterceptor(AuthStreamInterceptor(newTestAuthenticator())))
+
+	watch := func(ctx context.Context) error {
+		stream, err := client.Watch(ctx, &eventsv1.WatchRequest{Type: "deploy"})
+		require.NoError(t, err)
+		for {
+			if _, err := stream.Recv(); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(watch(context.Background())))
+	assert.Equal(t, codes.PermissionDenied, status.Code(watch(withKey("write-key"))), "writing does not grant reading")
+	assert.Equal(t, codes.PermissionDenied, status.Code(watch(withKey("read-key", "x-tenant-id", "team-b"))),
+		"keys without a tenant stay in the default one")
+	assert.NoError(t, watch(withKey("admin-key", "x-tenant-id", "team-b")))
+
+	svc.AssertExpectations(t)
+}
+
+func TestCancelStreamsWith(t *testing.T) {
+	stop, cancel := context.WithCancel(context.Background())
+	watching := make(chan struct{})
+
+	svc := new(MockEventService)
+	svc.On("WatchEvents", mock.Anything, "deploy").Run(func(args mock.Arguments) {
+		close(watching)
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.Canceled)
+	client := newTestClient(t, svc, grpc.ChainStreamInterceptor(
+		AuthStreamInterceptor(auth.NewAnonymousAuthenticator([]string{auth.ScopeEventsRead})),
+		CancelStreamsWith(stop),
+	))
+
+	stream, err := client.Watch(context.Background(), &eventsv1.WatchRequest{Type: "deploy"})
+	require.NoError(t, err)
+	<-watching
+	cancel()
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF, "the stream ends once the server stops")
+}
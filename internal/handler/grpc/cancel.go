@@ -0,0 +1,21 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// CancelStreamsWith cancels the context of streams once stop is done. A
+// Watch stream only ends when its context does, so without it GracefulStop
+// would wait for it until the shutdown deadline.
+func CancelStreamsWith(stop context.Context) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		unregister := context.AfterFunc(stop, cancel)
+		defer unregister()
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/service"
+	eventsv1 "github.com/godev/events-service/pkg/api/events/v1"
+)
+
+// maxListLimit is both the default and the largest page of List.
+const maxListLimit = 100
+
+type EventServer struct {
+	eventsv1.UnimplementedEventServiceServer
+
+	service service.IEventService
+	log     *zap.Logger
+}
+
+func NewEventServer(service service.IEventService) *EventServer {
+	return &EventServer{
+		service: service,
+		log:     logger.Get(),
+	}
+}
+
+func (s *EventServer) Register(server *grpc.Server) {
+	eventsv1.RegisterEventServiceServer(server, s)
+}
+
+func (s *EventServer) Start(ctx context.Context, req *eventsv1.StartRequest) (*eventsv1.StartResponse, error) {
+	s.log.Info("Starting event", zap.String("type", req.GetType()))
+
+	if err := s.service.StartEvent(ctx, req.GetType()); err != nil {
+		return nil, s.toStatus(err, "Failed to start event")
+	}
+
+	return &eventsv1.StartResponse{}, nil
+}
+
+func (s *EventServer) Finish(ctx context.Context, req *eventsv1.FinishRequest) (*eventsv1.FinishResponse, error) {
+	s.log.Info("Finishing event", zap.String("type", req.GetType()))
+
+	if err := s.service.FinishEvent(ctx, req.GetType()); err != nil {
+		return nil, s.toStatus(err, "Failed to finish event")
+	}
+
+	return &eventsv1.FinishResponse{}, nil
+}
+
+func (s *EventServer) List(ctx context.Context, req *eventsv1.ListRequest) (*eventsv1.ListResponse, error) {
+	limit := req.GetLimit()
+	if limit == 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	events, err := s.service.ListEvents(ctx, req.GetType(), req.GetOffset(), limit)
+	if err != nil {
+		return nil, s.toStatus(err, "Failed to list events")
+	}
+
+	resp := &eventsv1.ListResponse{Events: make([]*eventsv1.Event, 0, len(events))}
+	for _, event := range events {
+		resp.Events = append(resp.Events, toProtoEvent(event))
+	}
+
+	return resp, nil
+}
+
+func (s *EventServer) Get(ctx context.Context, req *eventsv1.GetRequest) (*eventsv1.GetResponse, error) {
+	event, err := s.service.GetEvent(ctx, req.GetId())
+	if err != nil {
+		return nil, s.toStatus(err, "Failed to get event")
+	}
+
+	return &eventsv1.GetResponse{Event: toProtoEvent(*event)}, nil
+}
+
+func (s *EventServer) Watch(req *eventsv1.WatchRequest, stream grpc.ServerStreamingServer[eventsv1.WatchResponse]) error {
+	s.log.Info("Watching events", zap.String("type", req.GetType()))
+
+	err := s.service.WatchEvents(stream.Context(), req.GetType(), func(event model.Event) error {
+		return stream.Send(&eventsv1.WatchResponse{Event: toProtoEvent(event)})
+	})
+	if err != nil && stream.Context().Err() == nil {
+		return s.toStatus(err, "Failed to watch events")
+	}
+
+	return nil
+}
+
+func (s *EventServer) toStatus(err error, message string) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidEventType),
+		errors.Is(err, service.ErrInvalidLimit),
+		errors.Is(err, service.ErrInvalidEventID):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrEventNotFound), errors.Is(err, service.ErrNoSuchEvent):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrVersionConflict), errors.Is(err, repository.ErrTransactionAborted):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, repository.ErrUnavailable):
+		s.log.Warn(message, zap.Error(err))
+		return status.Error(codes.Unavailable, message+": storage is unavailable")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		s.log.Error(message, zap.Error(err))
+		return status.Error(codes.Internal, message)
+	}
+}
+
+func toProtoEvent(event model.Event) *eventsv1.Event {
+	pb := &eventsv1.Event{
+		Id:        event.ID.Hex(),
+		Tenant:    event.Tenant,
+		Type:      event.Type,
+		State:     toProtoState(event.State),
+		StartedAt: timestamppb.New(event.StartedAt),
+		Version:   event.Version,
+	}
+	if event.FinishedAt != nil {
+		pb.FinishedAt = timestamppb.New(*event.FinishedAt)
+	}
+	return pb
+}
+
+func toProtoState(state model.EventState) eventsv1.EventState {
+	switch state {
+	case model.EventStateStarted:
+		return eventsv1.EventState_EVENT_STATE_STARTED
+	case model.EventStateFinished:
+		return eventsv1.EventState_EVENT_STATE_FINISHED
+	default:
+		return eventsv1.EventState_EVENT_STATE_UNSPECIFIED
+	}
+}
@@ -0,0 +1,265 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/service"
+	eventsv1 "github.com/godev/events-service/pkg/api/events/v1"
+)
+
+type MockEventService struct {
+	mock.Mock
+}
+
+func (m *MockEventService) ListEvents(ctx context.Context, eventType string, offset, limit int64) ([]model.Event, error) {
+	args := m.Called(ctx, eventType, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Event), args.Error(1)
+}
+
+func (m *MockEventService) GetEvent(ctx context.Context, id string) (*model.Event, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Event), args.Error(1)
+}
+
+func (m *MockEventService) GetEventHistory(ctx context.Context, id string) ([]model.HistoryEntry, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.HistoryEntry), args.Error(1)
+}
+
+func (m *MockEventService) GetStats(ctx context.Context, eventType string) ([]model.EventStats, error) {
+	args := m.Called(ctx, eventType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.EventStats), args.Error(1)
+}
+
+func (m *MockEventService) StartEvent(ctx context.Context, eventType string) error {
+	return m.Called(ctx, eventType).Error(0)
+}
+
+func (m *MockEventService) FinishEvent(ctx context.Context, eventType string) error {
+	return m.Called(ctx, eventType).Error(0)
+}
+
+func (m *MockEventService) ExecuteBatch(ctx context.Context, ops []model.BatchOperation, atomic bool) ([]error, bool, error) {
+	args := m.Called(ctx, ops, atomic)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).([]error), args.Bool(1), args.Error(2)
+}
+
+func (m *MockEventService) WatchEvents(ctx context.Context, eventType string, fn func(event model.Event) error) error {
+	args := m.Called(ctx, eventType)
+	if events, ok := args.Get(0).([]model.Event); ok {
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+// newTestClient serves svc over an in-memory connection with the server
+// options, such as interceptors, and returns a client of it.
+func newTestClient(t *testing.T, svc service.IEventService, opts ...grpc.ServerOption) eventsv1.EventServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(opts...)
+	srv := NewEventServer(svc)
+	srv.log = zap.NewNop()
+	srv.Register(server)
+
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return eventsv1.NewEventServiceClient(conn)
+}
+
+func TestEventServer_Start(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		expectCode codes.Code
+	}{
+		{name: "successful start", expectCode: codes.OK},
+		{name: "invalid event type", serviceErr: service.ErrInvalidEventType, expectCode: codes.InvalidArgument},
+		{name: "internal error", serviceErr: errors.New("repository error"), expectCode: codes.Internal},
+		{name: "storage unavailable", serviceErr: fmt.Errorf("%w: no primary", repository.ErrUnavailable), expectCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockEventService)
+			svc.On("StartEvent", mock.Anything, "deploy").Return(tt.serviceErr)
+			client := newTestClient(t, svc)
+
+			_, err := client.Start(context.Background(), &eventsv1.StartRequest{Type: "deploy"})
+			assert.Equal(t, tt.expectCode, status.Code(err))
+
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEventServer_Finish(t *testing.T) {
+	tests := []struct {
+		name       string
+		serviceErr error
+		expectCode codes.Code
+	}{
+		{name: "successful finish", expectCode: codes.OK},
+		{name: "no unfinished event", serviceErr: service.ErrEventNotFound, expectCode: codes.NotFound},
+		{name: "internal error", serviceErr: errors.New("repository error"), expectCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockEventService)
+			svc.On("FinishEvent", mock.Anything, "deploy").Return(tt.serviceErr)
+			client := newTestClient(t, svc)
+
+			_, err := client.Finish(context.Background(), &eventsv1.FinishRequest{Type: "deploy"})
+			assert.Equal(t, tt.expectCode, status.Code(err))
+
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEventServer_List(t *testing.T) {
+	startedAt := time.Now().Add(-time.Hour).UTC()
+	finishedAt := time.Now().UTC()
+	events := []model.Event{
+		{ID: primitive.NewObjectID(), Type: "deploy", State: model.EventStateStarted, StartedAt: startedAt, Version: 1},
+		{ID: primitive.NewObjectID(), Type: "deploy", State: model.EventStateFinished, StartedAt: startedAt, FinishedAt: &finishedAt, Version: 2},
+	}
+
+	svc := new(MockEventService)
+	svc.On("ListEvents", mock.Anything, "deploy", int64(5), int64(100)).Return(events, nil)
+	svc.On("ListEvents", mock.Anything, "Deploy", int64(0), int64(100)).Return(nil, service.ErrInvalidEventType)
+	client := newTestClient(t, svc)
+
+	resp, err := client.List(context.Background(), &eventsv1.ListRequest{Type: "deploy", Offset: 5})
+	require.NoError(t, err)
+	require.Len(t, resp.GetEvents(), 2)
+	assert.Equal(t, events[0].ID.Hex(), resp.GetEvents()[0].GetId())
+	assert.Equal(t, eventsv1.EventState_EVENT_STATE_STARTED, resp.GetEvents()[0].GetState())
+	assert.Nil(t, resp.GetEvents()[0].GetFinishedAt())
+	assert.Equal(t, eventsv1.EventState_EVENT_STATE_FINISHED, resp.GetEvents()[1].GetState())
+	assert.True(t, finishedAt.Equal(resp.GetEvents()[1].GetFinishedAt().AsTime()))
+
+	_, err = client.List(context.Background(), &eventsv1.ListRequest{Type: "deploy", Offset: 5, Limit: 1000})
+	require.NoError(t, err, "limits over the maximum are lowered, as in REST")
+
+	_, err = client.List(context.Background(), &eventsv1.ListRequest{Type: "Deploy"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	svc.AssertExpectations(t)
+}
+
+func TestEventServer_Get(t *testing.T) {
+	event := &model.Event{ID: primitive.NewObjectID(), Type: "deploy", State: model.EventStateStarted, Version: 1}
+
+	svc := new(MockEventService)
+	svc.On("GetEvent", mock.Anything, event.ID.Hex()).Return(event, nil)
+	svc.On("GetEvent", mock.Anything, "missing").Return(nil, service.ErrInvalidEventID)
+	svc.On("GetEvent", mock.Anything, "000000000000000000000000").Return(nil, service.ErrNoSuchEvent)
+	client := newTestClient(t, svc)
+
+	resp, err := client.Get(context.Background(), &eventsv1.GetRequest{Id: event.ID.Hex()})
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", resp.GetEvent().GetType())
+	assert.Equal(t, int64(1), resp.GetEvent().GetVersion())
+
+	_, err = client.Get(context.Background(), &eventsv1.GetRequest{Id: "missing"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Get(context.Background(), &eventsv1.GetRequest{Id: "000000000000000000000000"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	svc.AssertExpectations(t)
+}
+
+func TestEventServer_Watch(t *testing.T) {
+	events := []model.Event{
+		{ID: primitive.NewObjectID(), Type: "deploy", State: model.EventStateStarted, Version: 1},
+		{ID: primitive.NewObjectID(), Type: "deploy", State: model.EventStateFinished, Version: 2},
+	}
+
+	svc := new(MockEventService)
+	svc.On("WatchEvents", mock.Anything, "deploy").Return(events, nil)
+	client := newTestClient(t, svc)
+
+	stream, err := client.Watch(context.Background(), &eventsv1.WatchRequest{Type: "deploy"})
+	require.NoError(t, err)
+
+	var received []*eventsv1.Event
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		received = append(received, resp.GetEvent())
+	}
+
+	require.Len(t, received, 2)
+	assert.Equal(t, events[0].ID.Hex(), received[0].GetId())
+	assert.Equal(t, eventsv1.EventState_EVENT_STATE_FINISHED, received[1].GetState())
+
+	svc.AssertExpectations(t)
+}
+
+func TestEventServer_WatchInvalidType(t *testing.T) {
+	svc := new(MockEventService)
+	svc.On("WatchEvents", mock.Anything, "Deploy").Return(nil, service.ErrInvalidEventType)
+	client := newTestClient(t, svc)
+
+	stream, err := client.Watch(context.Background(), &eventsv1.WatchRequest{Type: "Deploy"})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/ratelimit"
+	"github.com/godev/events-service/internal/reqctx"
+)
+
+// RateLimitUnaryInterceptor limits unary calls per client and method like
+// the REST API limits routes, the full method name such as
+// "/events.v1.EventService/Start" taking the place of the route. Clients
+// are told their quota in "ratelimit-*" header metadata and rejected with
+// ResourceExhausted and "retry-after" once it is used up. It must run after
+// AuthUnaryInterceptor.
+func RateLimitUnaryInterceptor(limiter ratelimit.Limiter, policy *ratelimit.CurrentPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		limit, ok := policy.Get().For(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		result, err := limiter.Allow(ctx, info.FullMethod+" "+rateLimitClient(ctx), limit)
+		if err != nil {
+			// An unavailable limiter must not take the API down with it.
+			logger.FromContext(ctx).Error("Failed to check rate limit", zap.Error(err))
+			return handler(ctx, req)
+		}
+
+		md := metadata.Pairs(
+			"ratelimit-limit", strconv.Itoa(result.Limit),
+			"ratelimit-remaining", strconv.Itoa(result.Remaining),
+			"ratelimit-reset", seconds(result.ResetAfter),
+		)
+		if !result.Allowed {
+			md.Set("retry-after", seconds(result.RetryAfter))
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			logger.FromContext(ctx).Warn("Failed to set rate limit metadata", zap.Error(err))
+		}
+		if !result.Allowed {
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func rateLimitClient(ctx context.Context) string {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil || principal.Method == auth.MethodAnonymous {
+		return "ip:" + reqctx.ClientIP(ctx)
+	}
+	return principal.Method + ":" + principal.Subject
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
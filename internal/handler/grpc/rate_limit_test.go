@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/ratelimit"
+	eventsv1 "github.com/godev/events-service/pkg/api/events/v1"
+)
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	svc := new(MockEventService)
+	svc.On("StartEvent", mock.Anything, "deploy").Return(nil)
+	svc.On("ListEvents", mock.Anything, "", int64(0), int64(100)).Return([]model.Event{}, nil)
+
+	policy := ratelimit.NewCurrentPolicy(ratelimit.Policy{Routes: map[string]ratelimit.Limit{
+		eventsv1.EventService_Start_FullMethodName: {Rate: 0.1, Burst: 1},
+	}})
+	authenticator := auth.NewAnonymousAuthenticator([]string{auth.ScopeEventsRead, auth.ScopeEventsWrite})
+	client := newTestClient(t, svc, grpc.ChainUnaryInterceptor(
+		AuthUnaryInterceptor(authenticator),
+		RateLimitUnaryInterceptor(ratelimit.NewMemoryLimiter(), policy),
+	))
+
+	var header metadata.MD
+	_, err := client.Start(context.Background(), &eventsv1.StartRequest{Type: "deploy"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, header.Get("ratelimit-limit"))
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+	assert.Empty(t, header.Get("retry-after"))
+
+	_, err = client.Start(context.Background(), &eventsv1.StartRequest{Type: "deploy"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"10"}, header.Get("retry-after"))
+	svc.AssertNumberOfCalls(t, "StartEvent", 1)
+
+	// Methods without a limit are not limited.
+	for range 3 {
+		_, err = client.List(context.Background(), &eventsv1.ListRequest{})
+		require.NoError(t, err)
+	}
+}
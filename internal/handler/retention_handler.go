@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/retention"
+)
+
+type RetentionHandler struct {
+	base
+	job *retention.Job
+}
+
+func NewRetentionHandler(job *retention.Job) *RetentionHandler {
+	return &RetentionHandler{
+		job: job,
+	}
+}
+
+// Preview reports how many events of every tenant the next purge would
+// delete, per retention rule, without deleting anything.
+func (h *RetentionHandler) Preview(c *gin.Context) {
+	span := h.startSpan(c, "RetentionHandler.Preview")
+	defer h.endSpan(c, span)
+
+	previews, err := h.job.Preview(c.Request.Context(), time.Now())
+	if err != nil {
+		h.serverError(c, err, "Failed to preview retention")
+		return
+	}
+
+	c.JSON(http.StatusOK, model.RetentionPreviewResponse{Rules: previews})
+}
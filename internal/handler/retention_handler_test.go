@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/retention"
+)
+
+// countingStore counts expired events, or fails with err.
+type countingStore struct {
+	err error
+}
+
+func (s countingStore) Expired(context.Context, retention.Rule, int64) ([]model.Event, error) {
+	return nil, s.err
+}
+
+func (s countingStore) CountExpired(context.Context, retention.Rule) (int64, error) {
+	return 3, s.err
+}
+
+func (s countingStore) Delete(context.Context, []primitive.ObjectID) error {
+	return s.err
+}
+
+func TestPreviewRetention(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "counted", wantStatus: http.StatusOK},
+		{
+			name:           "storage unavailable",
+			err:            fmt.Errorf("%w: no reachable servers", repository.ErrUnavailable),
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "5",
+		},
+		{name: "storage failed", err: errors.New("bad query"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := retention.Policy{Default: 90 * 24 * time.Hour}
+			h := NewRetentionHandler(retention.NewJob(countingStore{err: tt.err}, nil, nil, policy, 100, zap.NewNop()))
+
+			w := serve(t, http.MethodGet, "/v1/admin/retention/preview", "", func(r *gin.Engine) {
+				r.GET("/v1/admin/retention/preview", h.Preview)
+			})
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantRetryAfter, w.Header().Get("Retry-After"))
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var resp model.RetentionPreviewResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Rules, 1)
+			assert.Equal(t, model.RetentionAllTypes, resp.Rules[0].Type)
+			assert.Equal(t, int64(3), resp.Rules[0].Count)
+		})
+	}
+}
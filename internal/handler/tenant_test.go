@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/config"
+	"github.com/godev/events-service/internal/db"
+	"github.com/godev/events-service/internal/middleware"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository/sqlite"
+	"github.com/godev/events-service/internal/service"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	database, err := db.NewSQLite(zap.NewNop(), &config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "events.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	repo, err := sqlite.NewEventRepository(database.GetDB())
+	require.NoError(t, err)
+	h := NewEventHandler(service.NewEventService(repo))
+
+	scopes := []string{auth.ScopeEventsRead, auth.ScopeEventsWrite}
+	keys := auth.NewStaticKeyStore([]auth.APIKey{
+		{Name: "a", Hash: auth.HashKey("a-key"), Scopes: scopes, Tenant: "team-a"},
+		{Name: "b", Hash: auth.HashKey("b-key"), Scopes: scopes, Tenant: "team-b"},
+		{Name: "unbound", Hash: auth.HashKey("unbound-key"), Scopes: scopes},
+		{Name: "ops", Hash: auth.HashKey("admin-key"), Scopes: []string{auth.ScopeAdmin}},
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v1 := router.Group("/v1", middleware.Authenticate(auth.NewAuthenticator(keys, nil)), middleware.Tenant())
+	v1.GET("", h.ListEvents)
+	v1.GET("/events/:id", h.GetEvent)
+	v1.POST("/start", h.StartEvent)
+
+	send := func(method, path, key, tenantHeader, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.APIKeyHeader, key)
+		if tenantHeader != "" {
+			req.Header.Set(tenant.Header, tenantHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	list := func(key, tenantHeader string) []model.Event {
+		w := send(http.MethodGet, "/v1", key, tenantHeader, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp model.EventsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Events
+	}
+
+	require.Equal(t, http.StatusOK, send(http.MethodPost, "/v1/start", "a-key", "", `{"type": "deploy"}`).Code)
+	eventsA := list("a-key", "")
+	require.Len(t, eventsA, 1)
+	assert.Equal(t, "team-a", eventsA[0].Tenant)
+
+	assert.Empty(t, list("b-key", ""), "team-b does not see team-a's events")
+	assert.Empty(t, list("unbound-key", ""), "unbound keys work in the default tenant")
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/v1/events/"+eventsA[0].ID.Hex(), "b-key", "", "").Code)
+
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/v1", "b-key", "team-a", "").Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/v1", "unbound-key", "team-a", "").Code,
+		"only admins choose a tenant")
+	assert.Len(t, list("admin-key", "team-a"), 1)
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+var errShuttingDown = errors.New("server is shutting down")
+
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker serves the liveness and readiness probes. Readiness runs every
+// registered check concurrently and fails as soon as shutdown has begun, so
+// that load balancers stop routing traffic before the server stops.
+type Checker struct {
+	timeout      time.Duration
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// AddCheck registers a readiness check. It must be called before serving.
+func (c *Checker) AddCheck(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Live reports that the process is up and able to serve HTTP.
+func (c *Checker) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, model.HealthResponse{Status: model.HealthStatusOK})
+}
+
+func (c *Checker) Ready(ctx *gin.Context) {
+	resp := c.Check(ctx.Request.Context())
+
+	status := http.StatusOK
+	if resp.Status != model.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, resp)
+}
+
+func (c *Checker) Check(ctx context.Context) model.HealthResponse {
+	resp := model.HealthResponse{
+		Status: model.HealthStatusOK,
+		Checks: make(map[string]model.HealthCheckResult, len(c.checks)+1),
+	}
+
+	if c.shuttingDown.Load() {
+		resp.Status = model.HealthStatusFail
+		resp.Checks["shutdown"] = model.HealthCheckResult{
+			Status: model.HealthStatusFail,
+			Error:  errShuttingDown.Error(),
+		}
+		return resp
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := chk.fn(ctx)
+			result := model.HealthCheckResult{
+				Status:     model.HealthStatusOK,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = model.HealthStatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[chk.name] = result
+			if err != nil {
+				resp.Status = model.HealthStatusFail
+			}
+		}(chk)
+	}
+	wg.Wait()
+
+	return resp
+}
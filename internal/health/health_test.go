@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+func serveReady(t *testing.T, checker *Checker) (int, model.HealthResponse) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", checker.Ready)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var resp model.HealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestChecker_Ready(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.AddCheck("mongodb", func(ctx context.Context) error { return nil })
+	checker.AddCheck("indexes", func(ctx context.Context) error { return nil })
+
+	code, resp := serveReady(t, checker)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, model.HealthStatusOK, resp.Status)
+	assert.Len(t, resp.Checks, 2)
+}
+
+func TestChecker_ReadyReportsFailingCheck(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.AddCheck("mongodb", func(ctx context.Context) error { return nil })
+	checker.AddCheck("mongodb_primary", func(ctx context.Context) error { return errors.New("no primary") })
+
+	code, resp := serveReady(t, checker)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, model.HealthStatusFail, resp.Status)
+	assert.Equal(t, model.HealthStatusOK, resp.Checks["mongodb"].Status)
+	assert.Equal(t, "no primary", resp.Checks["mongodb_primary"].Error)
+}
+
+func TestChecker_NotReadyWhenShuttingDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.AddCheck("mongodb", func(ctx context.Context) error { return nil })
+	checker.SetShuttingDown()
+
+	code, resp := serveReady(t, checker)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, model.HealthStatusFail, resp.Checks["shutdown"].Status)
+}
@@ -1,28 +1,86 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/godev/events-service/internal/config"
 )
 
-var log *zap.Logger
+var (
+	log   = zap.NewNop()
+	level = zap.NewAtomicLevel()
+)
+
+func Init(cfg *config.LogConfig) error {
+	lvl, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+	level.SetLevel(lvl)
+
+	zapConfig := zap.NewProductionConfig()
+	zapConfig.Level = level
+	zapConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 
-func Init() {
-	config := zap.NewProductionConfig()
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	switch cfg.Encoding {
+	case "json":
+		zapConfig.Encoding = "json"
+	case "console":
+		zapConfig.Encoding = "console"
+		zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		return fmt.Errorf("invalid log encoding %q: must be json or console", cfg.Encoding)
+	}
+
+	zapConfig.Sampling = nil
+	if cfg.SamplingInitial > 0 && cfg.SamplingThereafter > 0 {
+		zapConfig.Sampling = &zap.SamplingConfig{
+			Initial:    cfg.SamplingInitial,
+			Thereafter: cfg.SamplingThereafter,
+		}
+	}
 
-	var err error
-	log, err = config.Build()
+	built, err := zapConfig.Build()
 	if err != nil {
-		panic(err)
+		return err
 	}
+	log = built
+	return nil
 }
 
 func Get() *zap.Logger {
 	return log
 }
 
+// Level is the level shared by every logger built by Init. It implements
+// http.Handler: GET returns the current level, PUT with a body like
+// {"level":"debug"} changes it at runtime.
+func Level() zap.AtomicLevel {
+	return level
+}
+
+type loggerKey struct{}
+
+// WithContext stores a request-scoped logger, e.g. one annotated with the
+// request ID, in ctx.
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger stored by WithContext, or the global
+// logger when there is none.
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return log
+}
+
 func Sync() {
 	_ = log.Sync()
 }
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+const runningScrapeTimeout = 5 * time.Second
+
+// runningCacheTTL is how long a reading of the running events answers
+// scrapes, so that several scrapers do not each run the aggregation.
+const runningCacheTTL = 15 * time.Second
+
+type instrumentedRepository struct {
+	repository.IEventRepository
+	metrics *Metrics
+}
+
+// InstrumentRepository wraps repo so that started and finished events and
+// optimistic-lock conflicts are counted, and registers a gauge of the
+// currently running events per tenant and type that is read from repo on scrape.
+func (m *Metrics) InstrumentRepository(repo repository.IEventRepository) repository.IEventRepository {
+	m.registry.MustRegister(&runningCollector{repo: repo})
+	return &instrumentedRepository{IEventRepository: repo, metrics: m}
+}
+
+func (r *instrumentedRepository) Create(ctx context.Context, event *model.Event) error {
+	if err := r.IEventRepository.Create(ctx, event); err != nil {
+		return err
+	}
+	eventType := event.Type
+	record(ctx, func() { r.metrics.eventsStarted.WithLabelValues(eventType).Inc() })
+	return nil
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, event *model.Event) error {
+	err := r.IEventRepository.Update(ctx, event)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		r.metrics.conflicts.Inc()
+	}
+	if err != nil {
+		return err
+	}
+
+	if event.State == model.EventStateFinished && event.FinishedAt != nil {
+		eventType, duration := event.Type, event.FinishedAt.Sub(event.StartedAt)
+		record(ctx, func() {
+			r.metrics.eventsFinished.WithLabelValues(eventType).Inc()
+			r.metrics.finishDuration.WithLabelValues(eventType).Observe(duration.Seconds())
+		})
+	}
+	return nil
+}
+
+// WithTransaction counts the events started and finished in fn once the
+// transaction commits. fn may run several times when the transaction is
+// retried, and nothing it wrote is left when it is rolled back.
+func (r *instrumentedRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, nested := ctx.Value(pendingKey{}).(*pending); nested {
+		return r.IEventRepository.WithTransaction(ctx, fn)
+	}
+
+	p := &pending{}
+	err := r.IEventRepository.WithTransaction(ctx, func(txCtx context.Context) error {
+		p.updates = nil
+		return fn(context.WithValue(txCtx, pendingKey{}, p))
+	})
+	if err != nil {
+		return err
+	}
+	for _, update := range p.updates {
+		update()
+	}
+	return nil
+}
+
+type pendingKey struct{}
+
+// pending holds the metric updates of the transaction in progress.
+type pending struct {
+	updates []func()
+}
+
+// record applies update now, or when the transaction in ctx commits.
+func record(ctx context.Context, update func()) {
+	if p, ok := ctx.Value(pendingKey{}).(*pending); ok {
+		p.updates = append(p.updates, update)
+		return
+	}
+	update()
+}
+
+var runningDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "events_running"),
+	"Events that are started but not finished yet, by tenant and type.",
+	[]string{"tenant", "type"}, nil,
+)
+
+// runningCollector reads the running events from the database instead of
+// counting them in process, so the value is correct across restarts and
+// replicas. Readings are cached for runningCacheTTL.
+type runningCollector struct {
+	repo repository.IEventRepository
+
+	mu      sync.Mutex
+	stats   []model.EventStats
+	readAt  time.Time
+	timeNow func() time.Time
+}
+
+func (c *runningCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- runningDesc
+}
+
+func (c *runningCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.read()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(runningDesc, err)
+		return
+	}
+
+	for _, s := range stats {
+		ch <- prometheus.MustNewConstMetric(runningDesc, prometheus.GaugeValue, float64(s.Running), s.Tenant, s.Type)
+	}
+}
+
+func (c *runningCollector) read() ([]model.EventStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.timeNow != nil {
+		now = c.timeNow()
+	}
+	if !c.readAt.IsZero() && now.Sub(c.readAt) < runningCacheTTL {
+		return c.stats, nil
+	}
+
+	ctx, cancel := context.WithTimeout(tenant.WithAllTenants(context.Background()), runningScrapeTimeout)
+	defer cancel()
+
+	stats, err := c.repo.Stats(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	c.stats, c.readAt = stats, now
+	return stats, nil
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+)
+
+type fakeRepository struct {
+	repository.IEventRepository
+	updateErr  error
+	stats      []model.EventStats
+	statsCalls int
+	// attempts is how often WithTransaction runs its callback; commitErr
+	// is what it returns after the last attempt.
+	attempts  int
+	commitErr error
+}
+
+func (r *fakeRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	for i := 0; i < r.attempts; i++ {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+	return r.commitErr
+}
+
+func (r *fakeRepository) Create(ctx context.Context, event *model.Event) error {
+	return nil
+}
+
+func (r *fakeRepository) Update(ctx context.Context, event *model.Event) error {
+	return r.updateErr
+}
+
+func (r *fakeRepository) Stats(ctx context.Context, eventType string) ([]model.EventStats, error) {
+	r.statsCalls++
+	return r.stats, nil
+}
+
+func TestInstrumentRepository(t *testing.T) {
+	m := New()
+	fake := &fakeRepository{stats: []model.EventStats{
+		{Tenant: "default", Type: "deploy", Running: 2},
+		{Tenant: "payments", Type: "build", Running: 0},
+	}}
+	repo := m.InstrumentRepository(fake)
+
+	startedAt := time.Now().Add(-time.Minute)
+	finishedAt := startedAt.Add(30 * time.Second)
+	event := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: startedAt}
+
+	require.NoError(t, repo.Create(context.Background(), event))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.eventsStarted.WithLabelValues("deploy")))
+
+	event.State = model.EventStateFinished
+	event.FinishedAt = &finishedAt
+	require.NoError(t, repo.Update(context.Background(), event))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.eventsFinished.WithLabelValues("deploy")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.finishDuration))
+
+	fake.updateErr = repository.ErrVersionConflict
+	assert.ErrorIs(t, repo.Update(context.Background(), event), repository.ErrVersionConflict)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.conflicts))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.eventsFinished.WithLabelValues("deploy")))
+
+	expected := `
+# HELP events_service_events_running Events that are started but not finished yet, by tenant and type.
+# TYPE events_service_events_running gauge
+events_service_events_running{tenant="default",type="deploy"} 2
+events_service_events_running{tenant="payments",type="build"} 0
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected), "events_service_events_running"))
+}
+
+func TestInstrumentRepositoryCountsCommittedTransactions(t *testing.T) {
+	m := New()
+	fake := &fakeRepository{attempts: 3}
+	repo := m.InstrumentRepository(fake)
+
+	startedAt := time.Now().Add(-time.Minute)
+	finishedAt := startedAt.Add(30 * time.Second)
+	batch := func(ctx context.Context) error {
+		if err := repo.Create(ctx, &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: startedAt}); err != nil {
+			return err
+		}
+		return repo.Update(ctx, &model.Event{Type: "deploy", State: model.EventStateFinished, StartedAt: startedAt, FinishedAt: &finishedAt})
+	}
+
+	require.NoError(t, repo.WithTransaction(context.Background(), batch))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.eventsStarted.WithLabelValues("build")), "retried callbacks count once")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.eventsFinished.WithLabelValues("deploy")))
+
+	fake.commitErr = errors.New("commit failed")
+	assert.Error(t, repo.WithTransaction(context.Background(), batch))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.eventsStarted.WithLabelValues("build")), "rolled back writes are not counted")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.eventsFinished.WithLabelValues("deploy")))
+}
+
+func TestRunningCollectorCaches(t *testing.T) {
+	now := time.Now()
+	fake := &fakeRepository{stats: []model.EventStats{{Tenant: "default", Type: "deploy", Running: 1}}}
+	collector := &runningCollector{repo: fake, timeNow: func() time.Time { return now }}
+
+	assert.Equal(t, 1, testutil.CollectAndCount(collector))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector))
+	assert.Equal(t, 1, fake.statsCalls, "scrapes within the TTL reuse the reading")
+
+	now = now.Add(runningCacheTTL)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector))
+	assert.Equal(t, 2, fake.statsCalls)
+}
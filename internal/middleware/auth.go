@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/apierror"
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/repository"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// Authenticate identifies the caller from the Authorization or X-API-Key
+// header, or else from the client certificate verified by mutual TLS, and
+// stores the principal in the request context. Requests without valid
+// credentials get 401, and 503 while the key store cannot be reached.
+func Authenticate(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		creds := auth.ParseCredentials(c.GetHeader("Authorization"), c.GetHeader(APIKeyHeader))
+		if tls := c.Request.TLS; tls != nil && len(tls.VerifiedChains) > 0 {
+			creds.ClientCert = tls.VerifiedChains[0][0]
+		}
+
+		principal, err := authenticator.Authenticate(ctx, creds)
+		if err != nil {
+			if errors.Is(err, auth.ErrMissingCredentials) || errors.Is(err, auth.ErrInvalidCredentials) {
+				logger.FromContext(ctx).Info("Authentication failed", zap.Error(err))
+				c.Header("WWW-Authenticate", `Bearer realm="events-service"`)
+				apierror.Abort(c, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			if errors.Is(err, repository.ErrUnavailable) {
+				logger.FromContext(ctx).Warn("Failed to authenticate request", zap.Error(err))
+				apierror.RetryAfter(c, repository.RetryAfter)
+				apierror.Abort(c, http.StatusServiceUnavailable, "key store is unavailable")
+				return
+			}
+			logger.FromContext(ctx).Error("Failed to authenticate request", zap.Error(err))
+			apierror.Abort(c, http.StatusInternalServerError, "Failed to authenticate request")
+			return
+		}
+
+		ctx = auth.WithPrincipal(ctx, principal)
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(zap.String("actor", principal.Subject)))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose principal lacks scope with 403. It
+// must run after Authenticate.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := auth.PrincipalFromContext(c.Request.Context())
+		if principal == nil {
+			apierror.Abort(c, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !principal.HasScope(scope) {
+			apierror.Abort(c, http.StatusForbidden, "missing scope "+scope)
+			return
+		}
+
+		c.Next()
+	}
+}
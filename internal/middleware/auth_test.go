@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/repository"
+)
+
+func TestAuthenticateAndRequireScope(t *testing.T) {
+	keys := auth.NewStaticKeyStore([]auth.APIKey{
+		{Name: "reader", Hash: auth.HashKey("read-key"), Scopes: []string{auth.ScopeEventsRead}},
+		{Name: "ops", Hash: auth.HashKey("admin-key"), Scopes: []string{auth.ScopeAdmin}},
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v1 := router.Group("/v1", Authenticate(auth.NewAuthenticator(keys, nil)))
+	v1.GET("", RequireScope(auth.ScopeEventsRead), func(c *gin.Context) {
+		c.String(http.StatusOK, auth.PrincipalFromContext(c.Request.Context()).Subject)
+	})
+	v1.POST("/start", RequireScope(auth.ScopeEventsWrite), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{name: "no credentials", method: http.MethodGet, path: "/v1", expectedStatus: http.StatusUnauthorized},
+		{name: "unknown key", method: http.MethodGet, path: "/v1",
+			headers: map[string]string{APIKeyHeader: "nope"}, expectedStatus: http.StatusUnauthorized},
+		{name: "read key reads", method: http.MethodGet, path: "/v1",
+			headers: map[string]string{APIKeyHeader: "read-key"}, expectedStatus: http.StatusOK},
+		{name: "read key as bearer", method: http.MethodGet, path: "/v1",
+			headers: map[string]string{"Authorization": "Bearer read-key"}, expectedStatus: http.StatusOK},
+		{name: "read key cannot write", method: http.MethodPost, path: "/v1/start",
+			headers: map[string]string{APIKeyHeader: "read-key"}, expectedStatus: http.StatusForbidden},
+		{name: "admin key writes", method: http.MethodPost, path: "/v1/start",
+			headers: map[string]string{APIKeyHeader: "admin-key"}, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+type downKeyStore struct{}
+
+func (downKeyStore) Lookup(context.Context, string) (*auth.APIKey, error) {
+	return nil, fmt.Errorf("%w: no reachable servers", repository.ErrUnavailable)
+}
+
+func TestAuthenticateKeyStoreUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1", Authenticate(auth.NewAuthenticator(downKeyStore{}, nil)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1", nil)
+	req.Header.Set(APIKeyHeader, "read-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+}
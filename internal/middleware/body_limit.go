@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/godev/events-service/internal/apierror"
+)
+
+// MaxBodySize rejects requests that announce a body larger than limit with
+// 413 and cuts off bodies that turn out larger while they are read; reading
+// past limit fails with *http.MaxBytesError.
+func MaxBodySize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			apierror.Abort(c, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CancelWith cancels the context of the request once stop is done. Streams
+// such as /v1/watch only end when their context does, so without it a
+// graceful shutdown would wait for them until its deadline.
+func CancelWith(stop context.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		unregister := context.AfterFunc(stop, cancel)
+		defer unregister()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
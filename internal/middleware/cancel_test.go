@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelWith(t *testing.T) {
+	stop, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CancelWith(stop))
+	started := make(chan struct{})
+	router.GET("/watch", func(c *gin.Context) {
+		close(started)
+		<-c.Request.Context().Done()
+		c.Status(http.StatusNoContent)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watch", nil))
+		done <- w
+	}()
+
+	<-started
+	cancel()
+	select {
+	case w := <-done:
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	case <-time.After(time.Second):
+		require.Fail(t, "the request was not cancelled")
+	}
+}
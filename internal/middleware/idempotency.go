@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"bytes"
+	"container/heap"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/godev/events-service/internal/apierror"
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotentResponse struct {
+	key         string
+	digest      [sha256.Size]byte // of the request body
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+	index       int // position in the expiry heap, once done
+}
+
+// expiryHeap orders completed responses by expiry, so that the next one to
+// expire is always at the top.
+type expiryHeap []*idempotentResponse
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	resp := x.(*idempotentResponse)
+	resp.index = len(*h)
+	*h = append(*h, resp)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	resp := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return resp
+}
+
+// IdempotencyStore remembers responses to requests carrying an
+// Idempotency-Key so that a retried request is answered without being
+// executed twice. Responses are kept in memory for ttl; once the store
+// holds maxEntries keys, the responses closest to expiry are dropped early.
+type IdempotencyStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	responses  map[string]*idempotentResponse
+	expiry     expiryHeap
+}
+
+func NewIdempotencyStore(ttl time.Duration, maxEntries int) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		responses:  make(map[string]*idempotentResponse),
+	}
+}
+
+// reserve returns the stored response for key, or reserves key for the
+// request with the body digest when it has not been seen yet. It returns
+// false for both when the store is full of requests that are still running.
+func (s *IdempotencyStore) reserve(key string, digest [sha256.Size]byte, now time.Time) (*idempotentResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.expiry) > 0 && now.After(s.expiry[0].expiresAt) {
+		s.evict()
+	}
+
+	if resp, ok := s.responses[key]; ok {
+		return resp, false
+	}
+
+	if len(s.responses) >= s.maxEntries {
+		if len(s.expiry) == 0 {
+			return nil, false
+		}
+		s.evict()
+	}
+
+	s.responses[key] = &idempotentResponse{key: key, digest: digest}
+	return nil, true
+}
+
+// evict drops the response that expires first. s.mu must be held.
+func (s *IdempotencyStore) evict() {
+	resp := heap.Pop(&s.expiry).(*idempotentResponse)
+	delete(s.responses, resp.key)
+}
+
+func (s *IdempotencyStore) complete(key string, resp *idempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Server errors are not remembered so the client can retry them.
+	if resp.status >= http.StatusInternalServerError {
+		delete(s.responses, key)
+		return
+	}
+
+	resp.key = key
+	resp.done = true
+	resp.expiresAt = time.Now().Add(s.ttl)
+	s.responses[key] = resp
+	heap.Push(&s.expiry, resp)
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response for POST requests whose
+// Idempotency-Key was already processed. A request that arrives while the
+// first one with the same key is still running gets 409, one that reuses
+// the key with a different body 422.
+func Idempotency(store *IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+
+		// Keys are scoped to the caller and tenant so that one client cannot
+		// replay another client's response.
+		scopedKey := tenant.FromContext(c.Request.Context()) + " " + c.Request.Method + " " + c.FullPath() + " " + key
+		if principal := auth.PrincipalFromContext(c.Request.Context()); principal != nil {
+			scopedKey = principal.Method + ":" + principal.Subject + " " + scopedKey
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apierror.Abort(c, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			apierror.Abort(c, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		digest := sha256.Sum256(body)
+
+		stored, reserved := store.reserve(scopedKey, digest, time.Now())
+		if stored == nil && !reserved {
+			apierror.Abort(c, http.StatusServiceUnavailable, "too many requests with an idempotency key in progress")
+			return
+		}
+		if !reserved {
+			if stored.digest != digest {
+				apierror.Abort(c, http.StatusUnprocessableEntity, "idempotency key was used with a different request body")
+				return
+			}
+			if !stored.done {
+				apierror.Abort(c, http.StatusConflict, "request with this idempotency key is still in progress")
+				return
+			}
+			c.Header("Idempotent-Replayed", "true")
+			if stored.contentType != "" {
+				c.Data(stored.status, stored.contentType, stored.body)
+			} else {
+				c.Status(stored.status)
+			}
+			c.Abort()
+			return
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				store.complete(scopedKey, &idempotentResponse{digest: digest, status: http.StatusInternalServerError})
+				panic(r)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		store.complete(scopedKey, &idempotentResponse{
+			digest:      digest,
+			status:      recorder.Status(),
+			contentType: recorder.Header().Get("Content-Type"),
+			body:        recorder.body.Bytes(),
+		})
+	}
+}
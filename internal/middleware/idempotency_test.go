@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	status := http.StatusCreated
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/v1/start", Idempotency(NewIdempotencyStore(time.Hour, 10)), func(c *gin.Context) {
+		calls++
+		c.JSON(status, gin.H{"call": calls})
+	})
+
+	sendBody := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/start", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	send := func(key string) *httptest.ResponseRecorder {
+		return sendBody(key, "")
+	}
+
+	first := send("a")
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	replayed := send("a")
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), replayed.Body.String())
+	assert.Equal(t, 1, calls)
+
+	changed := sendBody("a", `{"type":"deploy"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, changed.Code, "the key was used with another body")
+	assert.Equal(t, 1, calls)
+
+	send("b")
+	send("")
+	send("")
+	assert.Equal(t, 4, calls, "other keys and requests without a key run")
+
+	status = http.StatusServiceUnavailable
+	send("c")
+	send("c")
+	assert.Equal(t, 6, calls, "server errors are not remembered")
+}
+
+func TestIdempotencyStoreExpires(t *testing.T) {
+	store := NewIdempotencyStore(time.Minute, 10)
+	now := time.Now()
+
+	_, reserved := store.reserve("a", [32]byte{}, now)
+	require.True(t, reserved)
+	stored, reserved := store.reserve("a", [32]byte{}, now)
+	require.False(t, reserved)
+	assert.False(t, stored.done, "the first request is still running")
+
+	store.complete("a", &idempotentResponse{status: http.StatusOK})
+	stored, reserved = store.reserve("a", [32]byte{}, now)
+	require.False(t, reserved)
+	assert.True(t, stored.done)
+
+	_, reserved = store.reserve("a", [32]byte{}, now.Add(2*time.Minute))
+	assert.True(t, reserved, "expired responses are dropped")
+}
+
+func TestIdempotencyStoreIsBounded(t *testing.T) {
+	store := NewIdempotencyStore(time.Hour, 2)
+	now := time.Now()
+
+	for _, key := range []string{"a", "b"} {
+		_, reserved := store.reserve(key, [32]byte{}, now)
+		require.True(t, reserved)
+	}
+	stored, reserved := store.reserve("c", [32]byte{}, now)
+	assert.Nil(t, stored)
+	assert.False(t, reserved, "running requests are never dropped")
+
+	store.complete("a", &idempotentResponse{status: http.StatusOK})
+	_, reserved = store.reserve("c", [32]byte{}, now)
+	assert.True(t, reserved, "the completed response makes room")
+	assert.Len(t, store.responses, 2)
+
+	stored, _ = store.reserve("a", [32]byte{}, now)
+	assert.Nil(t, stored, "the dropped response is not replayed")
+}
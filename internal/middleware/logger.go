@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/godev/events-service/internal/apierror"
+	"github.com/godev/events-service/internal/reqctx"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Logger writes one structured entry per request. Server errors are logged
+// at error level, client errors at warn. Requests to skipPaths, such as
+// health probes, are not logged.
+func Logger(log *zap.Logger, skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		if skip[path] {
+			return
+		}
+
+		status := c.Writer.Status()
+		lvl := zapcore.InfoLevel
+		switch {
+		case status >= http.StatusInternalServerError:
+			lvl = zapcore.ErrorLevel
+		case status >= http.StatusBadRequest:
+			lvl = zapcore.WarnLevel
+		}
+
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", path),
+			zap.String("route", c.FullPath()),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int("size", c.Writer.Size()),
+			zap.String("request_id", reqctx.RequestID(c.Request.Context())),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+
+		log.Log(lvl, "HTTP request", fields...)
+	}
+}
+
+// Recovery turns a panic in a handler into a 500 response and logs it with
+// the stack trace instead of printing to stderr.
+func Recovery(log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("Panic while handling request",
+					zap.Any("panic", r),
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path),
+					zap.String("request_id", reqctx.RequestID(c.Request.Context())),
+					zap.ByteString("stack", debug.Stack()))
+				apierror.Abort(c, http.StatusInternalServerError, "Internal server error")
+			}
+		}()
+		c.Next()
+	}
+}
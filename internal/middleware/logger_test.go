@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(core)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(log), Logger(log, "/healthz"), Recovery(log))
+	router.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/v1/finish", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/healthz", nil),
+		httptest.NewRequest(http.MethodPost, "/v1/finish", nil),
+		httptest.NewRequest(http.MethodGet, "/panic", nil),
+	} {
+		req.Header.Set(RequestIDHeader, "req-1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	requests := logs.FilterMessage("HTTP request").All()
+	require.Len(t, requests, 2)
+
+	finish := requests[0].ContextMap()
+	assert.Equal(t, zapcore.WarnLevel, requests[0].Level)
+	assert.Equal(t, "POST", finish["method"])
+	assert.Equal(t, "/v1/finish", finish["path"])
+	assert.Equal(t, int64(http.StatusNotFound), finish["status"])
+	assert.Equal(t, "req-1", finish["request_id"])
+	assert.Contains(t, finish, "latency")
+
+	assert.Equal(t, zapcore.ErrorLevel, requests[1].Level)
+	assert.Equal(t, int64(http.StatusInternalServerError), requests[1].ContextMap()["status"])
+	assert.Equal(t, 1, logs.FilterMessage("Panic while handling request").Len())
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/apierror"
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/ratelimit"
+)
+
+// RateLimit limits requests per client and route as configured by policy.
+// Clients are told their quota in RateLimit-* headers and rejected with 429
+// and Retry-After once it is used up. Authenticated clients are identified
+// by their credentials, anonymous ones by IP, so it must run after
+// Authenticate.
+func RateLimit(limiter ratelimit.Limiter, policy *ratelimit.CurrentPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		limit, ok := policy.Get().For(route)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		result, err := limiter.Allow(ctx, route+" "+rateLimitClient(c), limit)
+		if err != nil {
+			// An unavailable limiter must not take the API down with it.
+			logger.FromContext(ctx).Error("Failed to check rate limit", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.ResetAfter))
+		if !result.Allowed {
+			apierror.RetryAfter(c, result.RetryAfter)
+			apierror.Abort(c, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func rateLimitClient(c *gin.Context) string {
+	principal := auth.PrincipalFromContext(c.Request.Context())
+	if principal == nil || principal.Method == auth.MethodAnonymous {
+		return "ip:" + c.ClientIP()
+	}
+	return principal.Method + ":" + principal.Subject
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
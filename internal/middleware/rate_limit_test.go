@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/ratelimit"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	keys := auth.NewStaticKeyStore([]auth.APIKey{
+		{Name: "a", Hash: auth.HashKey("a-key"), Scopes: []string{auth.ScopeAdmin}},
+		{Name: "b", Hash: auth.HashKey("b-key"), Scopes: []string{auth.ScopeAdmin}},
+	})
+	policy := ratelimit.NewCurrentPolicy(ratelimit.Policy{Routes: map[string]ratelimit.Limit{"/v1/start": {Rate: 0.1, Burst: 2}}})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v1 := router.Group("/v1",
+		Authenticate(auth.NewAuthenticator(keys, nil)),
+		RateLimit(ratelimit.NewMemoryLimiter(), policy))
+	v1.POST("/start", func(c *gin.Context) { c.Status(http.StatusOK) })
+	v1.GET("", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/v1/start", "a-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", w.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/v1/start", "a-key").Code)
+
+	w = send(http.MethodPost, "/v1/start", "a-key")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+
+	// Other clients and unlimited routes are not affected.
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/v1/start", "b-key").Code)
+	w = send(http.MethodGet, "/v1", "a-key")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+
+	// A replaced policy applies from the next request on.
+	policy.Set(ratelimit.Policy{Default: ratelimit.Limit{Rate: 1, Burst: 5}})
+	w = send(http.MethodGet, "/v1", "a-key")
+	assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+
+	t.Run("limiter failure lets requests through", func(t *testing.T) {
+		router := gin.New()
+		router.POST("/v1/start", RateLimit(failingLimiter{}, policy), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/start", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestRateLimitKeysAnonymousClientsByPeer(t *testing.T) {
+	policy := ratelimit.NewCurrentPolicy(ratelimit.Policy{Default: ratelimit.Limit{Rate: 0.1, Burst: 1}})
+	newRouter := func(trusted []string) *gin.Engine {
+		router := gin.New()
+		if err := router.SetTrustedProxies(trusted); err != nil {
+			t.Fatal(err)
+		}
+		router.GET("/v1",
+			Authenticate(auth.NewAnonymousAuthenticator([]string{auth.ScopeEventsRead})),
+			RateLimit(ratelimit.NewMemoryLimiter(), policy),
+			func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	send := func(router *gin.Engine, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1", nil)
+		req.RemoteAddr = "192.0.2.1:40000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("forged header is ignored", func(t *testing.T) {
+		router := newRouter(nil)
+		assert.Equal(t, http.StatusOK, send(router, "198.51.100.1"))
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "198.51.100.2"),
+			"a new X-Forwarded-For must not buy a new quota")
+	})
+
+	t.Run("trusted proxy forwards the client", func(t *testing.T) {
+		router := newRouter([]string{"192.0.2.0/24"})
+		assert.Equal(t, http.StatusOK, send(router, "198.51.100.1"))
+		assert.Equal(t, http.StatusOK, send(router, "198.51.100.2"))
+		assert.Equal(t, http.StatusTooManyRequests, send(router, "198.51.100.1"))
+	})
+}
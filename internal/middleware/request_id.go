@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/reqctx"
+)
+
+const maxRequestIDLength = 128
+
+// RequestID accepts the caller's X-Request-ID or generates one, echoes it
+// in the response and stores it in the request context together with the
+// client IP and a logger that adds the ID to every entry.
+func RequestID(log *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Header(RequestIDHeader, id)
+
+		ctx := reqctx.WithRequestID(c.Request.Context(), id)
+		ctx = reqctx.WithClientIP(ctx, c.ClientIP())
+		ctx = logger.WithContext(ctx, log.With(zap.String("request_id", id)))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// validRequestID only lets through IDs that are safe to put in logs and
+// headers as-is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
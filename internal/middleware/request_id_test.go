@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/reqctx"
+)
+
+func TestRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(zap.New(core)), Recovery(zap.New(core)))
+	router.GET("/echo", func(c *gin.Context) {
+		logger.FromContext(c.Request.Context()).Info("handled")
+		c.String(http.StatusOK, reqctx.RequestID(c.Request.Context()))
+	})
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	tests := []struct {
+		name     string
+		header   string
+		expectID func(t *testing.T, id string)
+	}{
+		{
+			name:   "keeps caller id",
+			header: "ticket-42",
+			expectID: func(t *testing.T, id string) {
+				assert.Equal(t, "ticket-42", id)
+			},
+		},
+		{
+			name: "generates missing id",
+			expectID: func(t *testing.T, id string) {
+				assert.Len(t, id, 32)
+			},
+		},
+		{
+			name:   "replaces unsafe id",
+			header: "bad id\twith spaces",
+			expectID: func(t *testing.T, id string) {
+				assert.Len(t, id, 32)
+			},
+		},
+		{
+			name:   "replaces overlong id",
+			header: strings.Repeat("a", maxRequestIDLength+1),
+			expectID: func(t *testing.T, id string) {
+				assert.Len(t, id, 32)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/echo", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			tt.expectID(t, id)
+			assert.Equal(t, id, rec.Body.String())
+
+			entries := logs.TakeAll()
+			require.Len(t, entries, 1)
+			assert.Equal(t, id, entries[0].ContextMap()["request_id"])
+		})
+	}
+
+	t.Run("error response carries id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.Header.Set(RequestIDHeader, "ticket-43")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var resp model.ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "ticket-43", resp.RequestID)
+	})
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/apierror"
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/logger"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// Tenant resolves the tenant of the request from the principal or the
+// X-Tenant-ID header and stores it in the request context. It must run
+// after Authenticate.
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		principal := auth.PrincipalFromContext(ctx)
+		if principal == nil {
+			apierror.Abort(c, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		name, err := principal.ResolveTenant(c.GetHeader(tenant.Header))
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, auth.ErrTenantForbidden) {
+				status = http.StatusForbidden
+			}
+			apierror.Abort(c, status, err.Error())
+			return
+		}
+
+		ctx = tenant.WithTenant(ctx, name)
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(zap.String("tenant", name)))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+func TestTenant(t *testing.T) {
+	keys := auth.NewStaticKeyStore([]auth.APIKey{
+		{Name: "shared", Hash: auth.HashKey("shared-key"), Scopes: []string{auth.ScopeEventsRead}},
+		{Name: "team-a", Hash: auth.HashKey("a-key"), Scopes: []string{auth.ScopeEventsRead}, Tenant: "team-a"},
+		{Name: "ops", Hash: auth.HashKey("admin-key"), Scopes: []string{auth.ScopeAdmin}},
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v1 := router.Group("/v1", Authenticate(auth.NewAuthenticator(keys, nil)), Tenant())
+	v1.GET("", func(c *gin.Context) {
+		c.String(http.StatusOK, tenant.FromContext(c.Request.Context()))
+	})
+
+	tests := []struct {
+		name           string
+		key            string
+		header         string
+		expectedStatus int
+		expectedTenant string
+	}{
+		{name: "default tenant", key: "shared-key", expectedStatus: http.StatusOK, expectedTenant: tenant.Default},
+		{name: "unbound key default header", key: "shared-key", header: tenant.Default,
+			expectedStatus: http.StatusOK, expectedTenant: tenant.Default},
+		{name: "unbound key other tenant", key: "shared-key", header: "team-b", expectedStatus: http.StatusForbidden},
+		{name: "admin chooses by header", key: "admin-key", header: "team-b",
+			expectedStatus: http.StatusOK, expectedTenant: "team-b"},
+		{name: "admin default tenant", key: "admin-key", expectedStatus: http.StatusOK, expectedTenant: tenant.Default},
+		{name: "invalid header", key: "admin-key", header: "Team B", expectedStatus: http.StatusBadRequest},
+		{name: "bound key", key: "a-key", expectedStatus: http.StatusOK, expectedTenant: "team-a"},
+		{name: "bound key same header", key: "a-key", header: "team-a",
+			expectedStatus: http.StatusOK, expectedTenant: "team-a"},
+		{name: "bound key other tenant", key: "a-key", header: "team-b", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1", nil)
+			req.Header.Set(APIKeyHeader, tt.key)
+			if tt.header != "" {
+				req.Header.Set(tenant.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedTenant, w.Body.String())
+			}
+		})
+	}
+}
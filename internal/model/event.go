@@ -15,6 +15,7 @@ const (
 
 type Event struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Tenant     string             `bson:"tenant" json:"tenant"`
 	Type       string             `bson:"type" json:"type"`
 	State      EventState         `bson:"state" json:"state"`
 	StartedAt  time.Time          `bson:"started_at" json:"startedAt"`
@@ -31,7 +32,21 @@ type EventsResponse struct {
 }
 
 type ErrorResponse struct {
-	Message string `json:"message"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+type EventStats struct {
+	Tenant        string    `bson:"tenant" json:"tenant"`
+	Type          string    `bson:"type" json:"type"`
+	Running       int64     `bson:"running" json:"running"`
+	Finished      int64     `bson:"finished" json:"finished"`
+	AvgDurationMs float64   `bson:"avg_duration_ms" json:"avgDurationMs"`
+	LastStartedAt time.Time `bson:"last_started_at" json:"lastStartedAt"`
+}
+
+type StatsResponse struct {
+	Stats []EventStats `json:"stats"`
 }
 
 type EventFilter struct {
@@ -39,3 +54,144 @@ type EventFilter struct {
 	Offset    int64
 	Limit     int64
 }
+
+type BatchOperationType string
+
+const (
+	BatchOperationStart  BatchOperationType = "start"
+	BatchOperationFinish BatchOperationType = "finish"
+)
+
+type BatchOperation struct {
+	Op   BatchOperationType `json:"op"`
+	Type string             `json:"type"`
+}
+
+type BatchRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+type BatchResult struct {
+	Op      BatchOperationType `json:"op"`
+	Type    string             `json:"type"`
+	Status  int                `json:"status"`
+	Message string             `json:"message,omitempty"`
+}
+
+type BatchResponse struct {
+	// Committed is true when at least one operation was written; starting
+	// an event that is already running writes nothing.
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+type AuditAction string
+
+const (
+	AuditActionStart  AuditAction = "start"
+	AuditActionFinish AuditAction = "finish"
+	// AuditActionUpdate covers changes that are neither a start nor a finish.
+	AuditActionUpdate AuditAction = "update"
+)
+
+// AuditEntry records one mutation of an event. Entries are never changed
+// after they are written.
+type AuditEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Tenant    string             `bson:"tenant" json:"tenant"`
+	Actor     string             `bson:"actor" json:"actor"`
+	Action    AuditAction        `bson:"action" json:"action"`
+	EventID   primitive.ObjectID `bson:"event_id" json:"eventId"`
+	EventType string             `bson:"event_type" json:"eventType"`
+	// Before is unset for the entry that created the event.
+	Before    *EventState `bson:"before,omitempty" json:"before,omitempty"`
+	After     EventState  `bson:"after" json:"after"`
+	RequestID string      `bson:"request_id,omitempty" json:"requestId,omitempty"`
+	SourceIP  string      `bson:"source_ip,omitempty" json:"sourceIp,omitempty"`
+	At        time.Time   `bson:"at" json:"at"`
+}
+
+type AuditFilter struct {
+	Actor     string
+	Action    AuditAction
+	EventID   primitive.ObjectID
+	EventType string
+	// From and To bound At; zero values leave the range open.
+	From   time.Time
+	To     time.Time
+	Offset int64
+	Limit  int64
+}
+
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+type Transition string
+
+const (
+	TransitionStarted  Transition = "started"
+	TransitionFinished Transition = "finished"
+	// TransitionUpdated covers changes that are neither a start nor a finish.
+	TransitionUpdated Transition = "updated"
+	// TransitionConflict records an update rejected by optimistic locking;
+	// the event itself did not change.
+	TransitionConflict Transition = "conflict"
+)
+
+// HistoryEntry is one version of an event. Entries are never changed after
+// they are written.
+type HistoryEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EventID    primitive.ObjectID `bson:"event_id" json:"eventId"`
+	Tenant     string             `bson:"tenant" json:"tenant"`
+	Transition Transition         `bson:"transition" json:"transition"`
+	// Event is the event after the transition. For a conflict it is the
+	// rejected change, with the version it was based on.
+	Event Event `bson:"event" json:"event"`
+	// CurrentVersion is the version that was stored when a conflict
+	// happened.
+	CurrentVersion int64     `bson:"current_version,omitempty" json:"currentVersion,omitempty"`
+	Actor          string    `bson:"actor" json:"actor"`
+	RequestID      string    `bson:"request_id,omitempty" json:"requestId,omitempty"`
+	At             time.Time `bson:"at" json:"at"`
+}
+
+type HistoryResponse struct {
+	History []HistoryEntry `json:"history"`
+}
+
+// RetentionAllTypes is the type of the retention rule that covers every
+// type without a rule of its own.
+const RetentionAllTypes = "*"
+
+// RetentionPreview tells how many finished events a retention rule would
+// purge.
+type RetentionPreview struct {
+	Type           string    `json:"type"`
+	FinishedBefore time.Time `json:"finishedBefore"`
+	Count          int64     `json:"count"`
+}
+
+type RetentionPreviewResponse struct {
+	Rules []RetentionPreview `json:"rules"`
+}
+
+type HealthStatus string
+
+const (
+	HealthStatusOK   HealthStatus = "ok"
+	HealthStatusFail HealthStatus = "fail"
+)
+
+type HealthCheckResult struct {
+	Status     HealthStatus `json:"status"`
+	DurationMs int64        `json:"durationMs"`
+	Error      string       `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status HealthStatus                 `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
@@ -0,0 +1,23 @@
+// Package operation names the storage operations whose read preference and
+// concerns can be configured on their own. The configuration validates the
+// names and the repositories look their settings up by them.
+package operation
+
+const (
+	Get     = "get"
+	List    = "list"
+	Stats   = "stats"
+	History = "history"
+	Audit   = "audit"
+	Watch   = "watch"
+	Start   = "start"
+	Finish  = "finish"
+	Batch   = "batch"
+)
+
+// Reads only read. Writes run in a transaction, which always reads from the
+// primary.
+var (
+	Reads  = []string{Get, List, Stats, History, Audit, Watch}
+	Writes = []string{Start, Finish, Batch}
+)
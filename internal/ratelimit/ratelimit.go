@@ -0,0 +1,126 @@
+// Package ratelimit limits how often a client may call a route, using a
+// token bucket per client and route.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Limit is a token bucket that holds up to Burst requests and refills at
+// Rate requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of requests that may be made right away.
+	Remaining int
+	// RetryAfter is how long a rejected client has to wait for a token.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Limiter takes a token from the bucket of key. Buckets that do not exist
+// yet start full.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewResult describes a bucket that holds tokens after the request was
+// allowed or rejected.
+func NewResult(limit Limit, tokens float64, allowed bool) Result {
+	result := Result{
+		Allowed:    allowed,
+		Limit:      limit.Burst,
+		Remaining:  int(math.Max(0, math.Floor(tokens))),
+		ResetAfter: refillTime(limit, float64(limit.Burst)-tokens),
+	}
+	if !allowed {
+		result.RetryAfter = refillTime(limit, 1-tokens)
+	}
+	return result
+}
+
+func refillTime(limit Limit, tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / limit.Rate * float64(time.Second))
+}
+
+// Policy assigns limits to routes. Routes without a limit of their own use
+// Default; a zero Default leaves them unlimited.
+type Policy struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+func (p Policy) For(route string) (Limit, bool) {
+	if limit, ok := p.Routes[route]; ok {
+		return limit, true
+	}
+	return p.Default, p.Default.Burst > 0
+}
+
+// CurrentPolicy holds a policy that may be replaced while requests are
+// being limited by it.
+type CurrentPolicy struct {
+	policy atomic.Pointer[Policy]
+}
+
+func NewCurrentPolicy(policy Policy) *CurrentPolicy {
+	c := &CurrentPolicy{}
+	c.Set(policy)
+	return c
+}
+
+func (c *CurrentPolicy) Get() Policy {
+	return *c.policy.Load()
+}
+
+func (c *CurrentPolicy) Set(policy Policy) {
+	c.policy.Store(&policy)
+}
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit reads limits such as "10/s", "600/m" or "5/s:20": a number of
+// requests per second, minute or hour, optionally followed by the burst.
+// The burst defaults to the number of requests.
+func ParseLimit(s string) (Limit, error) {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+
+	count, unit, ok := strings.Cut(rate, "/")
+	period, known := units[unit]
+	if !ok || !known {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<s|m|h>[:<burst>]", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+
+	limit := Limit{Rate: float64(n) / period.Seconds(), Burst: n}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burst)
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	return limit, nil
+}
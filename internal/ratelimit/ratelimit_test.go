@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  Limit
+		expectErr bool
+	}{
+		{input: "10/s", expected: Limit{Rate: 10, Burst: 10}},
+		{input: "5/s:20", expected: Limit{Rate: 5, Burst: 20}},
+		{input: "600/m", expected: Limit{Rate: 10, Burst: 600}},
+		{input: "3600/h:1", expected: Limit{Rate: 1, Burst: 1}},
+		{input: "10", expectErr: true},
+		{input: "10/d", expectErr: true},
+		{input: "0/s", expectErr: true},
+		{input: "10/s:0", expectErr: true},
+		{input: "10/s:x", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			limit, err := ParseLimit(tt.input)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	start := Limit{Rate: 1, Burst: 2}
+	policy := Policy{Routes: map[string]Limit{"/v1/start": start}}
+
+	limit, ok := policy.For("/v1/start")
+	assert.True(t, ok)
+	assert.Equal(t, start, limit)
+
+	_, ok = policy.For("/v1")
+	assert.False(t, ok, "routes are unlimited without a default")
+
+	policy.Default = Limit{Rate: 10, Burst: 10}
+	limit, ok = policy.For("/v1")
+	assert.True(t, ok)
+	assert.Equal(t, policy.Default, limit)
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, result.ResetAfter)
+
+	// Other keys have their own bucket.
+	result, err = limiter.Allow(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// Full buckets are dropped on the next sweep.
+	now = now.Add(time.Hour)
+	_, err = limiter.Allow(ctx, "c", limit)
+	require.NoError(t, err)
+	assert.Len(t, limiter.buckets, 1)
+}
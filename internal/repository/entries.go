@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/reqctx"
+)
+
+// SystemActor is recorded for changes made outside of a request.
+const SystemActor = "system"
+
+// Actor names the caller of ctx.
+func Actor(ctx context.Context) string {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		return principal.Subject
+	}
+	return SystemActor
+}
+
+// NewAuditEntry describes a change of event made by the caller of ctx.
+// before is nil when the event was just created.
+func NewAuditEntry(ctx context.Context, action model.AuditAction, event *model.Event, before *model.EventState) *model.AuditEntry {
+	return &model.AuditEntry{
+		Tenant:    event.Tenant,
+		Actor:     Actor(ctx),
+		Action:    action,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Before:    before,
+		After:     event.State,
+		RequestID: reqctx.RequestID(ctx),
+		SourceIP:  reqctx.ClientIP(ctx),
+		At:        time.Now().UTC(),
+	}
+}
+
+// ChangeAction names the change from before to after for the audit log.
+func ChangeAction(before, after model.EventState) model.AuditAction {
+	if before == model.EventStateStarted && after == model.EventStateFinished {
+		return model.AuditActionFinish
+	}
+	return model.AuditActionUpdate
+}
+
+// NewHistoryEntry records event as it is after transition.
+func NewHistoryEntry(ctx context.Context, transition model.Transition, event model.Event) *model.HistoryEntry {
+	return &model.HistoryEntry{
+		EventID:    event.ID,
+		Tenant:     event.Tenant,
+		Transition: transition,
+		Event:      event,
+		Actor:      Actor(ctx),
+		RequestID:  reqctx.RequestID(ctx),
+		At:         time.Now().UTC(),
+	}
+}
+
+// NewConflictEntry records an update of event that was rejected because the
+// stored event, current, is at another version.
+func NewConflictEntry(ctx context.Context, event model.Event, current *model.Event) *model.HistoryEntry {
+	entry := NewHistoryEntry(ctx, model.TransitionConflict, event)
+	entry.Tenant = current.Tenant
+	entry.Event.Tenant = current.Tenant
+	entry.CurrentVersion = current.Version
+	return entry
+}
+
+// ChangeTransition names the change from before to after for the history.
+func ChangeTransition(before, after model.EventState) model.Transition {
+	if before == model.EventStateStarted && after == model.EventStateFinished {
+		return model.TransitionFinished
+	}
+	return model.TransitionUpdated
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrVersionConflict = errors.New("event was modified by another process")
+	// ErrUnfinishedExists is returned by Create when the tenant already has
+	// an unfinished event of the same type.
+	ErrUnfinishedExists = errors.New("an unfinished event of this type already exists")
+	// ErrTransactionAborted is returned by a write that joined a transaction
+	// the storage aborted when the write failed, so that the transaction
+	// cannot go on; the request may succeed when retried.
+	ErrTransactionAborted = errors.New("transaction was aborted by a conflicting write")
+	// ErrUnavailable wraps the errors of a storage that cannot be reached
+	// or is not ready yet; the request may succeed when retried later.
+	ErrUnavailable = errors.New("storage is unavailable")
+)
+
+// RetryAfter is how long clients are asked to wait before retrying a
+// request that failed with ErrUnavailable.
+const RetryAfter = 5 * time.Second
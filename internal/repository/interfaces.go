@@ -3,12 +3,30 @@ package repository
 import (
 	"context"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
 	"github.com/godev/events-service/internal/model"
 )
 
 type IEventRepository interface {
 	Create(ctx context.Context, event *model.Event) error
-	FindUnfinishedByType(ctx context.Context, eventType string) (*model.Event, error)
+	// FindUnfinishedByType returns the running event of the type, or nil.
+	// op is operation.Start or operation.Finish, whichever the lookup is
+	// part of, and picks the read settings where a backend has them.
+	FindUnfinishedByType(ctx context.Context, eventType, op string) (*model.Event, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*model.Event, error)
 	Update(ctx context.Context, event *model.Event) error
+	// History returns the transitions of the event in the order they
+	// happened.
+	History(ctx context.Context, id primitive.ObjectID) ([]model.HistoryEntry, error)
 	List(ctx context.Context, eventType string, offset, limit int64) ([]model.Event, error)
+	Stats(ctx context.Context, eventType string) ([]model.EventStats, error)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	Watch(ctx context.Context, eventType string, fn func(event model.Event) error) error
+}
+
+// IAuditRepository reads the audit log. Entries are written by
+// IEventRepository in the same transaction as the change they record.
+type IAuditRepository interface {
+	List(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
 }
@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/godev/events-service/internal/auth"
+)
+
+var apiKeyIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+type APIKeyStore struct {
+	collection *mongo.Collection
+}
+
+// NewAPIKeyStore serves API keys from the named collection. Keys are
+// managed directly in MongoDB, so revoking one takes effect on the next
+// request. Its indexes are those of APIKeyIndexes.
+func NewAPIKeyStore(db *mongo.Database, collection string) auth.KeyStore {
+	return &APIKeyStore{collection: db.Collection(collection)}
+}
+
+func (s *APIKeyStore) Lookup(ctx context.Context, hash string) (*auth.APIKey, error) {
+	var key auth.APIKey
+	err := s.collection.FindOne(ctx, bson.M{
+		"hash":     hash,
+		"disabled": bson.M{"$ne": true},
+	}).Decode(&key)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, unavailable(err)
+	}
+
+	return &key, nil
+}
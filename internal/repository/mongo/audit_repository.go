@@ -0,0 +1,104 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tenant"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+const auditCollection = "audit"
+
+var auditIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "at", Value: -1},
+		},
+	},
+	{
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "event_id", Value: 1},
+			{Key: "at", Value: -1},
+		},
+	},
+	{
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "actor", Value: 1},
+			{Key: "at", Value: -1},
+		},
+	},
+}
+
+type AuditRepository struct {
+	collection *mongo.Collection
+}
+
+// NewAuditRepository reads the audit log that EventRepository writes; the
+// indexes of the collection are part of EventIndexes. ops may set the read
+// preference and concern of operation.Audit.
+func NewAuditRepository(db *mongo.Database, ops map[string]*options.CollectionOptions) repository.IAuditRepository {
+	return &AuditRepository{collection: forOperation(db.Collection(auditCollection), ops, operation.Audit)}
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter model.AuditFilter) (_ []model.AuditEntry, err error) {
+	ctx, span := tracing.Start(ctx, "AuditRepository.List",
+		attribute.String("audit.actor", filter.Actor),
+		attribute.String("audit.action", string(filter.Action)))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	query := bson.M{}
+	if !tenant.All(ctx) {
+		query["tenant"] = tenant.FromContext(ctx)
+	}
+	if filter.Actor != "" {
+		query["actor"] = filter.Actor
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if !filter.EventID.IsZero() {
+		query["event_id"] = filter.EventID
+	}
+	if filter.EventType != "" {
+		query["event_type"] = filter.EventType
+	}
+	at := bson.M{}
+	if !filter.From.IsZero() {
+		at["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		at["$lt"] = filter.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(filter.Offset).
+		SetLimit(filter.Limit)
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []model.AuditEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,17 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/repository/repotest"
+)
+
+func TestAuditRepository(t *testing.T) {
+	client := testClient(t)
+
+	repotest.RunAudit(t, func(t *testing.T) (repository.IEventRepository, repository.IAuditRepository) {
+		db := testDatabase(t, client)
+		return NewEventRepository(db, nil), NewAuditRepository(db, nil)
+	})
+}
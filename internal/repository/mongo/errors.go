@@ -0,0 +1,31 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+
+	"github.com/godev/events-service/internal/repository"
+)
+
+// unavailable wraps err in repository.ErrUnavailable when it means that the
+// deployment cannot be reached: a network error, no server to select or a
+// primary that stepped down. Other errors are returned as they are.
+func unavailable(err error) error {
+	if err == nil || errors.Is(err, repository.ErrUnavailable) || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var selection topology.ServerSelectionError
+	var server mongo.ServerError
+	switch {
+	case mongo.IsNetworkError(err),
+		errors.As(err, &selection),
+		errors.As(err, &server) && server.HasErrorLabel("RetryableWriteError"):
+		return fmt.Errorf("%w: %w", repository.ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,249 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+const logCollection = "event_log"
+
+// transitionImported marks a record that seeds the log with an event that
+// was stored before the event-sourced mode was enabled.
+const transitionImported model.Transition = "imported"
+
+var logIndexes = []mongo.IndexModel{
+	{
+		// Versions of an event are unique, so the log cannot fork.
+		Keys: bson.D{
+			{Key: "event_id", Value: 1},
+			{Key: "version", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	},
+}
+
+// logRecord is one change of an event in the log. It carries the complete
+// state of the event after the change, so the projection of an event is its
+// last record.
+type logRecord struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	EventID    primitive.ObjectID `bson:"event_id"`
+	Tenant     string             `bson:"tenant"`
+	Version    int64              `bson:"version"`
+	Transition model.Transition   `bson:"transition"`
+	Type       string             `bson:"type"`
+	State      model.EventState   `bson:"state"`
+	StartedAt  time.Time          `bson:"started_at"`
+	FinishedAt *time.Time         `bson:"finished_at,omitempty"`
+	Actor      string             `bson:"actor"`
+	At         time.Time          `bson:"at"`
+}
+
+func newLogRecord(ctx context.Context, transition model.Transition, event model.Event) *logRecord {
+	return &logRecord{
+		EventID:    event.ID,
+		Tenant:     event.Tenant,
+		Version:    event.Version,
+		Transition: transition,
+		Type:       event.Type,
+		State:      event.State,
+		StartedAt:  event.StartedAt,
+		FinishedAt: event.FinishedAt,
+		Actor:      repository.Actor(ctx),
+		At:         time.Now().UTC(),
+	}
+}
+
+// apply returns the event as it is after rec.
+func (rec *logRecord) apply() model.Event {
+	return model.Event{
+		ID:         rec.EventID,
+		Tenant:     rec.Tenant,
+		Type:       rec.Type,
+		State:      rec.State,
+		StartedAt:  rec.StartedAt,
+		FinishedAt: rec.FinishedAt,
+		Version:    rec.Version,
+	}
+}
+
+// EventSourcedRepository appends every start and finish to an immutable log
+// and keeps the events collection as a projection of it. Writes are not
+// replayed from the log: each one updates the projection like the state
+// mode does and appends the resulting state to the log in the same
+// transaction, so the two cannot drift apart, and Rebuild restores the
+// projection from the log if it is lost. Reads are served from the
+// projection.
+type EventSourcedRepository struct {
+	*EventRepository
+}
+
+// NewEventSourcedRepository needs the indexes of EventLogIndexes next to
+// those of EventIndexes.
+func NewEventSourcedRepository(db *mongo.Database, ops map[string]*options.CollectionOptions) *EventSourcedRepository {
+	repo := NewEventRepository(db, ops).(*EventRepository)
+	repo.log = db.Collection(logCollection)
+	return &EventSourcedRepository{EventRepository: repo}
+}
+
+// appendLog records the change that turned an event into event. It does
+// nothing unless the repository is event-sourced.
+func (r *EventRepository) appendLog(ctx context.Context, transition model.Transition, event model.Event) error {
+	if r.log == nil {
+		return nil
+	}
+
+	_, err := r.log.InsertOne(ctx, newLogRecord(ctx, transition, event))
+	if mongo.IsDuplicateKeyError(err) {
+		return repository.ErrVersionConflict
+	}
+	return err
+}
+
+// Rebuild regenerates the projection of every event from the log. It
+// returns how many projections it wrote and how many events it re-imported.
+// Events stored before the log existed are imported into it first.
+//
+// The log of an event may be incomplete, e.g. when the event was changed
+// while the service ran in state mode. Every record carries the complete
+// state of the event, so a log with gaps is still projected from its last
+// record. A projection that is ahead of the log is left alone; it is
+// re-imported into the log unless it was updated while rebuilding, in
+// which case the log has the new version already. Any other conflict, such
+// as two unfinished events of one type, fails the rebuild.
+func (r *EventSourcedRepository) Rebuild(ctx context.Context) (rebuilt, reimported int, err error) {
+	ctx, span := tracing.Start(ctx, "EventSourcedRepository.Rebuild")
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	if err := r.importUnlogged(ctx); err != nil {
+		return 0, 0, fmt.Errorf("import events: %w", err)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "event_id", Value: 1}, {Key: "version", Value: 1}})
+	cursor, err := r.log.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var current *model.Event
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		_, err := r.collection.ReplaceOne(ctx,
+			bson.M{"_id": current.ID, "version": bson.M{"$lte": current.Version}},
+			current, options.Replace().SetUpsert(true))
+		if mongo.IsDuplicateKeyError(err) {
+			ahead, aheadErr := r.projectionAhead(ctx, *current)
+			if aheadErr != nil {
+				return aheadErr
+			}
+			if !ahead {
+				return fmt.Errorf("project event %s version %d: %w", current.ID.Hex(), current.Version, err)
+			}
+			done, err := r.reimport(ctx, current.ID)
+			if done {
+				reimported++
+			}
+			return err
+		}
+		if err == nil {
+			rebuilt++
+		}
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var rec logRecord
+		if err := cursor.Decode(&rec); err != nil {
+			return rebuilt, reimported, err
+		}
+
+		if current == nil || current.ID != rec.EventID {
+			if err := flush(); err != nil {
+				return rebuilt, reimported, err
+			}
+		}
+		event := rec.apply()
+		current = &event
+	}
+	if err := cursor.Err(); err != nil {
+		return rebuilt, reimported, err
+	}
+
+	return rebuilt, reimported, flush()
+}
+
+// projectionAhead reports whether the stored projection of event has a
+// later version than the log.
+func (r *EventSourcedRepository) projectionAhead(ctx context.Context, event model.Event) (bool, error) {
+	n, err := r.collection.CountDocuments(ctx, bson.M{"_id": event.ID, "version": bson.M{"$gt": event.Version}})
+	return n > 0, err
+}
+
+// reimport appends the stored state of the event with id to the log and
+// reports whether it did. It does not when the log has that version
+// already.
+func (r *EventSourcedRepository) reimport(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	var stored model.Event
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Deleted meanwhile, e.g. by retention.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = r.appendLog(ctx, transitionImported, stored)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// importUnlogged seeds the log with the current state of every event that
+// has no records yet.
+func (r *EventSourcedRepository) importUnlogged(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         logCollection,
+			"localField":   "_id",
+			"foreignField": "event_id",
+			"pipeline":     bson.A{bson.M{"$limit": 1}},
+			"as":           "log",
+		}}},
+		{{Key: "$match", Value: bson.M{"log": bson.M{"$size": 0}}}},
+		{{Key: "$project", Value: bson.M{"log": 0}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var event model.Event
+		if err := cursor.Decode(&event); err != nil {
+			return err
+		}
+		err := r.appendLog(ctx, transitionImported, event)
+		if err != nil && !errors.Is(err, repository.ErrVersionConflict) {
+			return err
+		}
+	}
+	return cursor.Err()
+}
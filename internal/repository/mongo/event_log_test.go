@@ -0,0 +1,176 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/repository/repotest"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+func TestEventSourcedRepository(t *testing.T) {
+	client := testClient(t)
+
+	repotest.Run(t, func(t *testing.T) repository.IEventRepository {
+		return NewEventSourcedRepository(testDatabase(t, client), nil)
+	})
+}
+
+func TestEventSourcedRepository_Rebuild(t *testing.T) {
+	db := testDatabase(t, testClient(t))
+	repo := NewEventSourcedRepository(db, nil)
+	ctx := context.Background()
+
+	finished := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	require.NoError(t, repo.Create(ctx, finished))
+	finishedAt := time.Now().UTC().Truncate(time.Millisecond)
+	finished.State = model.EventStateFinished
+	finished.FinishedAt = &finishedAt
+	require.NoError(t, repo.Update(ctx, finished))
+
+	running := &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	require.NoError(t, repo.Create(ctx, running))
+
+	// An event stored before the log existed.
+	legacy := model.Event{
+		ID:        primitive.NewObjectID(),
+		Tenant:    tenant.Default,
+		Type:      "legacy",
+		State:     model.EventStateStarted,
+		StartedAt: time.Now().UTC().Truncate(time.Millisecond),
+		Version:   3,
+	}
+	_, err := repo.collection.InsertOne(ctx, legacy)
+	require.NoError(t, err)
+
+	// Lose the projection of one event and corrupt the other.
+	_, err = repo.collection.DeleteOne(ctx, bson.M{"_id": finished.ID})
+	require.NoError(t, err)
+	_, err = repo.collection.UpdateOne(ctx, bson.M{"_id": running.ID}, bson.M{"$set": bson.M{"type": "broken"}})
+	require.NoError(t, err)
+
+	rebuilt, reimported, err := repo.Rebuild(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rebuilt)
+	assert.Zero(t, reimported)
+
+	found, err := repo.FindByID(ctx, finished.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, model.EventStateFinished, found.State)
+	assert.Equal(t, int64(2), found.Version)
+	require.NotNil(t, found.FinishedAt)
+	assert.True(t, finishedAt.Equal(*found.FinishedAt))
+
+	found, err = repo.FindByID(ctx, running.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "build", found.Type)
+
+	found, err = repo.FindByID(ctx, legacy.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, int64(3), found.Version)
+
+	imported, err := repo.log.CountDocuments(ctx, bson.M{"event_id": legacy.ID, "transition": transitionImported})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), imported)
+
+	// The legacy event continues from its imported version.
+	found.State = model.EventStateFinished
+	found.FinishedAt = &finishedAt
+	require.NoError(t, repo.Update(ctx, found))
+	_, _, err = repo.Rebuild(ctx)
+	require.NoError(t, err)
+}
+
+func TestEventSourcedRepository_RebuildIncompleteLog(t *testing.T) {
+	db := testDatabase(t, testClient(t))
+	repo := NewEventSourcedRepository(db, nil)
+	ctx := context.Background()
+
+	// Changed while the service ran in state mode: the log misses version 2
+	// and the stored event is ahead of it.
+	skipped := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	require.NoError(t, repo.Create(ctx, skipped))
+	_, err := repo.collection.UpdateOne(ctx, bson.M{"_id": skipped.ID},
+		bson.M{"$set": bson.M{"state": model.EventStateFinished, "version": 2}})
+	require.NoError(t, err)
+
+	// A log that lost a record projects from its last one.
+	gapped := &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	require.NoError(t, repo.Create(ctx, gapped))
+	finish := func(event *model.Event) {
+		at := time.Now().UTC().Truncate(time.Millisecond)
+		event.State = model.EventStateFinished
+		event.FinishedAt = &at
+		require.NoError(t, repo.Update(ctx, event))
+	}
+	finish(gapped)
+	_, err = repo.log.DeleteOne(ctx, bson.M{"event_id": gapped.ID, "version": 1})
+	require.NoError(t, err)
+	_, err = repo.collection.DeleteOne(ctx, bson.M{"_id": gapped.ID})
+	require.NoError(t, err)
+
+	rebuilt, reimported, err := repo.Rebuild(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rebuilt)
+	assert.Equal(t, 1, reimported)
+
+	found, err := repo.FindByID(ctx, gapped.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, model.EventStateFinished, found.State)
+
+	found, err = repo.FindByID(ctx, skipped.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, int64(2), found.Version)
+	last, err := repo.log.CountDocuments(ctx, bson.M{"event_id": skipped.ID, "version": 2, "transition": transitionImported})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), last, "the stored event is re-imported")
+
+	_, reimported, err = repo.Rebuild(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, reimported, "the log has caught up")
+}
+
+func TestEventSourcedRepository_RebuildReportsConflicts(t *testing.T) {
+	db := testDatabase(t, testClient(t))
+	repo := NewEventSourcedRepository(db, nil)
+	ctx := context.Background()
+
+	running := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	require.NoError(t, repo.Create(ctx, running))
+
+	// A log that says two deploys are running at once.
+	second := model.Event{
+		ID:        primitive.NewObjectID(),
+		Tenant:    tenant.Default,
+		Type:      "deploy",
+		State:     model.EventStateStarted,
+		StartedAt: time.Now().UTC().Truncate(time.Millisecond),
+		Version:   1,
+	}
+	_, err := repo.log.InsertOne(ctx, newLogRecord(ctx, model.TransitionStarted, second))
+	require.NoError(t, err)
+
+	_, reimported, err := repo.Rebuild(ctx)
+	require.Error(t, err)
+	assert.True(t, mongo.IsDuplicateKeyError(err))
+	assert.Contains(t, err.Error(), second.ID.Hex())
+	assert.Zero(t, reimported, "the conflict is not taken for a projection ahead of the log")
+
+	found, err := repo.FindByID(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
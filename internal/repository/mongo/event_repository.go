@@ -7,64 +7,142 @@ import (
 	"github.com/godev/events-service/internal/repository"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
+	"github.com/godev/events-service/internal/tenant"
+	"github.com/godev/events-service/internal/tracing"
 )
 
 type EventRepository struct {
 	collection *mongo.Collection
+	audit      *mongo.Collection
+	history    *mongo.Collection
+	// log is set when the repository is event-sourced.
+	log *mongo.Collection
+	// ops holds the read preference and concerns of single operations.
+	ops map[string]*options.CollectionOptions
 }
 
-func NewEventRepository(db *mongo.Database) repository.IEventRepository {
-	collection := db.Collection("events")
+// obsoleteEventIndexes were replaced by the tenant-scoped indexes below.
+var obsoleteEventIndexes = []string{"type_1_state_1"}
 
-	indexes := []mongo.IndexModel{
-		{
-			Keys: bson.D{
-				{Key: "type", Value: 1},
-				{Key: "state", Value: 1},
-			},
+var eventIndexes = []mongo.IndexModel{
+	{
+		// At most one unfinished event per type within a tenant.
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "type", Value: 1},
 		},
-		{
-			Keys: bson.D{
-				{Key: "started_at", Value: -1},
-			},
+		Options: options.Index().
+			SetName("tenant_1_type_1_unfinished").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"state": model.EventStateStarted}),
+	},
+	{
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "type", Value: 1},
+			{Key: "state", Value: 1},
 		},
+	},
+	{
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "started_at", Value: -1},
+		},
+	},
+}
+
+// NewEventRepository stores events in the events collection. It does not
+// touch the database; the indexes of EventIndexes have to be built before
+// it is written to, see Indexer. ops overrides the settings of the database
+// for the operations of operation.Reads and operation.Writes.
+func NewEventRepository(db *mongo.Database, ops map[string]*options.CollectionOptions) repository.IEventRepository {
+	return &EventRepository{
+		collection: db.Collection("events"),
+		audit:      db.Collection(auditCollection),
+		history:    db.Collection(historyCollection),
+		ops:        ops,
 	}
+}
 
-	_, err := collection.Indexes().CreateMany(context.Background(), indexes)
-	if err != nil {
-		panic(err)
+// forOperation returns coll with the settings configured for op.
+func forOperation(coll *mongo.Collection, ops map[string]*options.CollectionOptions, op string) *mongo.Collection {
+	opts, ok := ops[op]
+	if !ok {
+		return coll
 	}
+	return coll.Database().Collection(coll.Name(), opts)
+}
 
-	return &EventRepository{
-		collection: collection,
+// scoped restricts filter to the tenant of ctx.
+func scoped(ctx context.Context, filter bson.M) bson.M {
+	if !tenant.All(ctx) {
+		filter["tenant"] = tenant.FromContext(ctx)
 	}
+	return filter
 }
 
-func (r *EventRepository) Create(ctx context.Context, event *model.Event) error {
-	session, err := r.collection.Database().Client().StartSession()
-	if err != nil {
-		return err
+func (r *EventRepository) Create(ctx context.Context, event *model.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Create", attribute.String("event.type", event.Type))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	if !tenant.All(ctx) || event.Tenant == "" {
+		event.Tenant = tenant.FromContext(ctx)
 	}
-	defer session.EndSession(ctx)
 
-	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+
+	joined := mongo.SessionFromContext(ctx) != nil
+	return r.transaction(ctx, operation.Start, func(sessCtx context.Context) error {
 		event.Version = 1
 		_, err := r.collection.InsertOne(sessCtx, event)
-		return nil, err
+		if mongo.IsDuplicateKeyError(err) {
+			// MongoDB aborts the transaction on the failed insert, so the
+			// caller's transaction cannot go on as if nothing was written.
+			if joined {
+				return repository.ErrTransactionAborted
+			}
+			return repository.ErrUnfinishedExists
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = r.audit.InsertOne(sessCtx, repository.NewAuditEntry(sessCtx, model.AuditActionStart, event, nil))
+		if err != nil {
+			return err
+		}
+
+		_, err = r.history.InsertOne(sessCtx, repository.NewHistoryEntry(sessCtx, model.TransitionStarted, *event))
+		if err != nil {
+			return err
+		}
+
+		return r.appendLog(sessCtx, model.TransitionStarted, *event)
 	})
-	return err
 }
 
-func (r *EventRepository) FindUnfinishedByType(ctx context.Context, eventType string) (*model.Event, error) {
+func (r *EventRepository) FindUnfinishedByType(ctx context.Context, eventType, op string) (_ *model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.FindUnfinishedByType", attribute.String("event.type", eventType))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	// The lookup is part of starting or finishing an event, so it reads
+	// with the settings of that operation rather than those of the database.
 	var event model.Event
-	err := r.collection.FindOne(ctx, bson.M{
+	err = forOperation(r.collection, r.ops, op).FindOne(ctx, scoped(ctx, bson.M{
 		"type":  eventType,
 		"state": model.EventStateStarted,
-	}).Decode(&event)
+	})).Decode(&event)
 
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return nil, nil
@@ -73,18 +151,35 @@ func (r *EventRepository) FindUnfinishedByType(ctx context.Context, eventType st
 	return &event, err
 }
 
-func (r *EventRepository) Update(ctx context.Context, event *model.Event) error {
-	session, err := r.collection.Database().Client().StartSession()
-	if err != nil {
-		return err
+func (r *EventRepository) FindByID(ctx context.Context, id primitive.ObjectID) (_ *model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.FindByID", attribute.String("event.id", id.Hex()))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	var event model.Event
+	err = forOperation(r.collection, r.ops, operation.Get).FindOne(ctx, scoped(ctx, bson.M{"_id": id})).Decode(&event)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
 	}
-	defer session.EndSession(ctx)
 
-	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
-		filter := bson.M{
+	return &event, err
+}
+
+func (r *EventRepository) Update(ctx context.Context, event *model.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Update",
+		attribute.String("event.id", event.ID.Hex()),
+		attribute.String("event.type", event.Type),
+		attribute.Int64("event.version", event.Version))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	// current is the stored event when the update loses a version race.
+	var current *model.Event
+	err = r.transaction(ctx, operation.Finish, func(sessCtx context.Context) error {
+		current = nil
+		filter := scoped(sessCtx, bson.M{
 			"_id":     event.ID,
 			"version": event.Version,
-		}
+		})
 
 		update := bson.M{
 			"$set": bson.M{
@@ -94,22 +189,54 @@ func (r *EventRepository) Update(ctx context.Context, event *model.Event) error
 			},
 		}
 
-		result, err := r.collection.UpdateOne(sessCtx, filter, update)
+		// The previous state of the event goes into the audit entry.
+		var before model.Event
+		err := r.collection.FindOneAndUpdate(sessCtx, filter, update).Decode(&before)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			var stored model.Event
+			err = r.collection.FindOne(sessCtx, scoped(sessCtx, bson.M{"_id": event.ID})).Decode(&stored)
+			if err == nil {
+				current = &stored
+			} else if !errors.Is(err, mongo.ErrNoDocuments) {
+				return err
+			}
+			return repository.ErrVersionConflict
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if result.ModifiedCount == 0 {
-			return nil, errors.New("event was modified by another process")
+		entry := repository.NewAuditEntry(sessCtx, repository.ChangeAction(before.State, event.State), event, &before.State)
+		entry.Tenant = before.Tenant
+		if _, err = r.audit.InsertOne(sessCtx, entry); err != nil {
+			return err
 		}
 
-		return nil, nil
+		after := before
+		after.State = event.State
+		after.FinishedAt = event.FinishedAt
+		after.Version = event.Version + 1
+		change := repository.ChangeTransition(before.State, after.State)
+		if _, err = r.history.InsertOne(sessCtx, repository.NewHistoryEntry(sessCtx, change, after)); err != nil {
+			return err
+		}
+
+		return r.appendLog(sessCtx, change, after)
 	})
+
+	if errors.Is(err, repository.ErrVersionConflict) && current != nil {
+		if herr := r.recordConflict(ctx, *event, current); herr != nil {
+			err = errors.Join(err, herr)
+		}
+	}
 	return err
 }
 
-func (r *EventRepository) List(ctx context.Context, eventType string, offset, limit int64) ([]model.Event, error) {
-	filter := bson.M{}
+func (r *EventRepository) List(ctx context.Context, eventType string, offset, limit int64) (_ []model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.List", attribute.String("event.type", eventType))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	filter := scoped(ctx, bson.M{})
 	if eventType != "" {
 		filter["type"] = eventType
 	}
@@ -119,7 +246,7 @@ func (r *EventRepository) List(ctx context.Context, eventType string, offset, li
 		SetSkip(offset).
 		SetLimit(limit)
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cursor, err := forOperation(r.collection, r.ops, operation.List).Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -132,3 +259,127 @@ func (r *EventRepository) List(ctx context.Context, eventType string, offset, li
 
 	return events, nil
 }
+
+func (r *EventRepository) Stats(ctx context.Context, eventType string) (_ []model.EventStats, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Stats", attribute.String("event.type", eventType))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	match := scoped(ctx, bson.M{})
+	if eventType != "" {
+		match["type"] = eventType
+	}
+
+	finished := bson.M{"$eq": bson.A{"$state", model.EventStateFinished}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"tenant": "$tenant", "type": "$type"},
+			"running": bson.M{"$sum": bson.M{
+				"$cond": bson.A{bson.M{"$eq": bson.A{"$state", model.EventStateStarted}}, 1, 0},
+			}},
+			"finished": bson.M{"$sum": bson.M{"$cond": bson.A{finished, 1, 0}}},
+			"avg_duration_ms": bson.M{"$avg": bson.M{
+				"$cond": bson.A{finished, bson.M{"$subtract": bson.A{"$finished_at", "$started_at"}}, nil},
+			}},
+			"last_started_at": bson.M{"$max": "$started_at"},
+		}}},
+		{{Key: "$set", Value: bson.M{"tenant": "$_id.tenant", "type": "$_id.type"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "tenant", Value: 1}, {Key: "type", Value: 1}}}},
+	}
+
+	cursor, err := forOperation(r.collection, r.ops, operation.Stats).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []model.EventStats
+	if err = cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// WithTransaction runs fn inside a MongoDB transaction. When ctx already
+// carries a session (e.g. a batch of operations), fn joins that transaction
+// instead of starting a nested one.
+func (r *EventRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.transaction(ctx, operation.Batch, fn)
+}
+
+// transaction is WithTransaction with the read and write concerns
+// configured for op. Reads in a transaction must go to the primary, which
+// is set explicitly so that a database or client that prefers secondaries
+// does not make every transaction fail.
+func (r *EventRepository) transaction(ctx context.Context, op string, fn func(ctx context.Context) error) (err error) {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	ctx, span := tracing.Start(ctx, "EventRepository.WithTransaction")
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// The driver retries the callback on transient errors; every attempt is
+	// recorded so slow transactions can be told apart from slow queries.
+	attempt := 0
+	txn := options.Transaction().SetReadPreference(readpref.Primary())
+	if opts := r.ops[op]; opts != nil {
+		txn.SetReadConcern(opts.ReadConcern).SetWriteConcern(opts.WriteConcern)
+	}
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		attempt++
+		span.AddEvent("transaction.attempt", trace.WithAttributes(attribute.Int("attempt", attempt)))
+		return nil, fn(sessCtx)
+	}, txn)
+	span.SetAttributes(attribute.Int("transaction.attempts", attempt))
+	return err
+}
+
+// Watch tails the collection change stream and calls fn with the current
+// state of every inserted or updated event until ctx is done or fn fails.
+func (r *EventRepository) Watch(ctx context.Context, eventType string, fn func(event model.Event) error) error {
+	match := bson.M{
+		"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}},
+	}
+	if !tenant.All(ctx) {
+		match["fullDocument.tenant"] = tenant.FromContext(ctx)
+	}
+	if eventType != "" {
+		match["fullDocument.type"] = eventType
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+
+	stream, err := forOperation(r.collection, r.ops, operation.Watch).Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return unavailable(err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change struct {
+			FullDocument *model.Event `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		// The document may already be gone by the time the update is looked up.
+		if change.FullDocument == nil {
+			continue
+		}
+		if err := fn(*change.FullDocument); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return unavailable(stream.Err())
+}
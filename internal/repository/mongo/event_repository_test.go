@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/repository/repotest"
+)
+
+// testClient connects to MONGODB_TEST_URI and skips the test when it is not
+// set. The event repository suite needs a replica set for transactions, e.g.
+// MONGODB_TEST_URI=mongodb://localhost:27017/?replicaSet=rs0. opts are
+// applied over the URI.
+func testClient(t *testing.T, opts ...*options.ClientOptions) *mongo.Client {
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+
+	client, err := mongo.Connect(context.Background(), append([]*options.ClientOptions{options.Client().ApplyURI(uri)}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client
+}
+
+// testDatabase returns a database with the indexes of every repository,
+// which is dropped when t ends.
+func testDatabase(t *testing.T, client *mongo.Client) *mongo.Database {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	db := client.Database("events_test_" + hex.EncodeToString(suffix))
+	t.Cleanup(func() { _ = db.Drop(context.Background()) })
+
+	sets := append(EventIndexes(), EventLogIndexes()...)
+	sets = append(sets, RetentionIndexes()...)
+	sets = append(sets, RateLimitIndexes("rate_limits")...)
+	require.NoError(t, EnsureIndexes(context.Background(), db, sets...))
+	return db
+}
+
+func TestEventRepository(t *testing.T) {
+	client := testClient(t)
+
+	repotest.Run(t, func(t *testing.T) repository.IEventRepository {
+		return NewEventRepository(testDatabase(t, client), nil)
+	})
+}
+
+// TestEventRepositorySecondaryPreferred runs the suite on a client that
+// prefers secondaries, as MONGODB_READ_PREFERENCE may configure:
+// transactions must still read from the primary. On a replica set with a
+// single member, like the one of docker-compose.yml, the reads outside
+// transactions see every write too.
+func TestEventRepositorySecondaryPreferred(t *testing.T) {
+	client := testClient(t, options.Client().SetReadPreference(readpref.SecondaryPreferred()))
+
+	repotest.Run(t, func(t *testing.T) repository.IEventRepository {
+		return NewEventRepository(testDatabase(t, client), nil)
+	})
+}
+
+func TestEventRepositoryWithOperations(t *testing.T) {
+	client := testClient(t)
+	majority := options.Collection().
+		SetReadConcern(&readconcern.ReadConcern{Level: "majority"}).
+		SetWriteConcern(writeconcern.Majority())
+	ops := map[string]*options.CollectionOptions{
+		operation.List:   options.Collection().SetReadPreference(readpref.SecondaryPreferred()),
+		operation.Stats:  options.Collection().SetReadPreference(readpref.Nearest()),
+		operation.Get:    options.Collection().SetReadConcern(&readconcern.ReadConcern{Level: "local"}),
+		operation.Start:  majority,
+		operation.Finish: majority,
+		operation.Batch:  majority,
+	}
+
+	repotest.Run(t, func(t *testing.T) repository.IEventRepository {
+		return NewEventRepository(testDatabase(t, client), ops)
+	})
+}
+
+// TestCreateAbortsTheJoinedTransaction checks that a duplicate start inside
+// a transaction fails it: MongoDB has aborted it by then, so treating the
+// duplicate as a no-op would fail the writes after it.
+func TestCreateAbortsTheJoinedTransaction(t *testing.T) {
+	repo := NewEventRepository(testDatabase(t, testClient(t)), nil)
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}))
+
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, repo.Create(ctx, &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: time.Now()}))
+		return repo.Create(ctx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()})
+	})
+	require.ErrorIs(t, err, repository.ErrTransactionAborted)
+	assert.NotErrorIs(t, err, repository.ErrUnfinishedExists)
+
+	builds, err := repo.List(ctx, "build", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, builds, "the transaction is rolled back")
+
+	err = repo.Create(ctx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()})
+	assert.ErrorIs(t, err, repository.ErrUnfinishedExists, "outside a transaction the duplicate is reported as such")
+}
@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+const historyCollection = "event_history"
+
+var historyIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "tenant", Value: 1},
+			{Key: "event_id", Value: 1},
+			{Key: "_id", Value: 1},
+		},
+	},
+}
+
+// recordConflict stores an update of event that was rejected because the
+// stored event is at version current. The update may be part of a larger
+// transaction, such as a batch, that is rolled back because of the
+// conflict, so the entry is written outside of any session to outlive it.
+func (r *EventRepository) recordConflict(ctx context.Context, event model.Event, current *model.Event) error {
+	ctx = mongo.NewSessionContext(ctx, nil)
+	_, err := r.history.InsertOne(ctx, repository.NewConflictEntry(ctx, event, current))
+	return err
+}
+
+func (r *EventRepository) History(ctx context.Context, id primitive.ObjectID) (_ []model.HistoryEntry, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.History", attribute.String("event.id", id.Hex()))
+	defer func() { err = unavailable(err); tracing.End(span, err) }()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := forOperation(r.history, r.ops, operation.History).Find(ctx, scoped(ctx, bson.M{"event_id": id}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []model.HistoryEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
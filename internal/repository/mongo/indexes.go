@@ -0,0 +1,298 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/backoff"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+)
+
+// IndexSet is the indexes one collection needs. Documents that would keep
+// them from being built are fixed by the migrations, which run first.
+type IndexSet struct {
+	collection string
+	models     []mongo.IndexModel
+	// obsolete names indexes that the models superseded; they are dropped.
+	obsolete []string
+}
+
+// EventIndexes are the indexes of the events, audit and history
+// collections that EventRepository writes. Creating them also creates the
+// audit and history collections, which older servers cannot do inside the
+// transactions that write to them.
+func EventIndexes() []IndexSet {
+	return []IndexSet{
+		{collection: "events", models: eventIndexes, obsolete: obsoleteEventIndexes},
+		{collection: auditCollection, models: auditIndexes},
+		{collection: historyCollection, models: historyIndexes},
+	}
+}
+
+// EventLogIndexes are the indexes of the log of EventSourcedRepository.
+func EventLogIndexes() []IndexSet {
+	return []IndexSet{{collection: logCollection, models: logIndexes}}
+}
+
+// RetentionIndexes are the indexes RetentionStore finds expired events with.
+func RetentionIndexes() []IndexSet {
+	return []IndexSet{{collection: "events", models: retentionIndexes}}
+}
+
+// APIKeyIndexes are the indexes of the collection of APIKeyStore.
+func APIKeyIndexes(collection string) []IndexSet {
+	return []IndexSet{{collection: collection, models: apiKeyIndexes}}
+}
+
+// RateLimitIndexes are the indexes of the collection of RateLimiter.
+func RateLimitIndexes(collection string) []IndexSet {
+	return []IndexSet{{collection: collection, models: rateLimitIndexes}}
+}
+
+// EnsureIndexes creates the indexes of sets that do not exist yet and drops
+// the ones they superseded.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, sets ...IndexSet) error {
+	for _, set := range sets {
+		coll := db.Collection(set.collection)
+		if _, err := coll.Indexes().CreateMany(ctx, set.models); err != nil {
+			return fmt.Errorf("create indexes of %s: %w", set.collection, unavailable(err))
+		}
+		for _, name := range set.obsolete {
+			_, err := coll.Indexes().DropOne(ctx, name)
+			if err != nil && !isIndexNotFound(err) {
+				return fmt.Errorf("drop index %s of %s: %w", name, set.collection, unavailable(err))
+			}
+		}
+	}
+	return nil
+}
+
+// indexNotFoundCode is what dropping an index that does not exist fails with.
+const indexNotFoundCode = 27
+
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == indexNotFoundCode
+}
+
+// Building an index fails with these codes when it conflicts with the
+// documents or with an index of the same name; building it again fails the
+// same way until someone fixes the data or drops the index.
+const (
+	indexOptionsConflictCode  = 85
+	indexKeySpecsConflictCode = 86
+)
+
+func isPermanentIndexError(err error) bool {
+	var server mongo.ServerError
+	return errors.As(err, &server) &&
+		(server.HasErrorCode(duplicateKeyCode) ||
+			server.HasErrorCode(indexOptionsConflictCode) ||
+			server.HasErrorCode(indexKeySpecsConflictCode))
+}
+
+// IndexState is how far an Indexer got.
+type IndexState string
+
+const (
+	IndexesPending  IndexState = "pending"
+	IndexesBuilding IndexState = "building"
+	IndexesReady    IndexState = "ready"
+	// IndexesFailed means that a build failed in a way retrying cannot fix,
+	// such as duplicates of a unique index; it needs an operator.
+	IndexesFailed IndexState = "failed"
+)
+
+// IndexStatus reports the progress of an Indexer.
+type IndexStatus struct {
+	State IndexState `json:"state"`
+	// Attempts counts the builds started so far; Error is why the last
+	// one failed.
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error,omitempty"`
+	ReadyAt  *time.Time `json:"ready_at,omitempty"`
+}
+
+// Indexer builds indexes in the background so that the server does not
+// have to wait for MongoDB to start. Failed builds are retried with backoff
+// until they succeed, except for conflicts with the stored documents or
+// existing indexes, which leave the indexes failed.
+type Indexer struct {
+	db      *mongo.Database
+	backoff backoff.Backoff
+	log     *zap.Logger
+
+	mu     sync.Mutex
+	sets   []IndexSet
+	status IndexStatus
+}
+
+func NewIndexer(db *mongo.Database, b backoff.Backoff, log *zap.Logger) *Indexer {
+	return &Indexer{
+		db:      db,
+		backoff: b,
+		log:     log,
+		status:  IndexStatus{State: IndexesPending},
+	}
+}
+
+// Add registers more indexes to build. It must be called before Run.
+func (i *Indexer) Add(sets ...IndexSet) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.sets = append(i.sets, sets...)
+}
+
+// Run builds the registered indexes, retrying until they are built, fail
+// for good or ctx is done.
+func (i *Indexer) Run(ctx context.Context) {
+	i.mu.Lock()
+	sets := i.sets
+	i.mu.Unlock()
+
+	start := time.Now()
+	err := backoff.Retry(ctx, i.backoff, func(ctx context.Context) error {
+		i.update(func(s *IndexStatus) {
+			s.State = IndexesBuilding
+			s.Attempts++
+		})
+		err := EnsureIndexes(ctx, i.db, sets...)
+		if isPermanentIndexError(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, func(attempt int, err error, wait time.Duration) {
+		i.update(func(s *IndexStatus) { s.Error = err.Error() })
+		i.log.Warn("Failed to build indexes",
+			zap.Error(err),
+			zap.Int("attempt", attempt),
+			zap.Duration("retry_in", wait))
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			i.update(func(s *IndexStatus) { s.Error = err.Error() })
+			return
+		}
+		i.update(func(s *IndexStatus) {
+			s.State = IndexesFailed
+			s.Error = err.Error()
+		})
+		i.log.Error("Failed to build indexes, not retrying", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	i.update(func(s *IndexStatus) {
+		s.State = IndexesReady
+		s.Error = ""
+		s.ReadyAt = &now
+	})
+	i.log.Info("Indexes built", zap.Duration("took", now.Sub(start)))
+}
+
+func (i *Indexer) update(fn func(*IndexStatus)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	fn(&i.status)
+}
+
+func (i *Indexer) Status() IndexStatus {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.status
+}
+
+// Ready returns nil once the indexes are built, else an error wrapping
+// repository.ErrUnavailable that tells how far the build got.
+func (i *Indexer) Ready() error {
+	status := i.Status()
+	if status.State == IndexesReady {
+		return nil
+	}
+	if status.Error != "" {
+		return fmt.Errorf("%w: indexes are %s, attempt %d failed: %s",
+			repository.ErrUnavailable, status.State, status.Attempts, status.Error)
+	}
+	return fmt.Errorf("%w: indexes are %s", repository.ErrUnavailable, status.State)
+}
+
+// Check reports whether the indexes are built and, once they are, that
+// none of the indexes of the events collection went missing since.
+func (i *Indexer) Check(ctx context.Context) error {
+	if err := i.Ready(); err != nil {
+		return err
+	}
+	return CheckIndexes(ctx, i.db)
+}
+
+// RequireIndexes makes repo refuse to write with repository.ErrUnavailable
+// until indexer has built the indexes: the unique index is what keeps a
+// type from having two unfinished events, and duplicates written before it
+// exists would keep it from ever being built.
+func RequireIndexes(repo repository.IEventRepository, indexer *Indexer) repository.IEventRepository {
+	return &indexedRepository{IEventRepository: repo, indexer: indexer}
+}
+
+type indexedRepository struct {
+	repository.IEventRepository
+	indexer *Indexer
+}
+
+func (r *indexedRepository) Create(ctx context.Context, event *model.Event) error {
+	if err := r.indexer.Ready(); err != nil {
+		return err
+	}
+	return r.IEventRepository.Create(ctx, event)
+}
+
+func (r *indexedRepository) Update(ctx context.Context, event *model.Event) error {
+	if err := r.indexer.Ready(); err != nil {
+		return err
+	}
+	return r.IEventRepository.Update(ctx, event)
+}
+
+// CheckIndexes reports an error when one of the indexes the repository
+// relies on is missing from the events collection.
+func CheckIndexes(ctx context.Context, db *mongo.Database) error {
+	specs, err := db.Collection("events").Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		existing[spec.Name] = true
+	}
+
+	for _, index := range eventIndexes {
+		name := indexName(index)
+		if !existing[name] {
+			return fmt.Errorf("index %s is missing", name)
+		}
+	}
+	return nil
+}
+
+// indexName returns the name MongoDB generates for an index without an
+// explicit name, e.g. "type_1_state_1".
+func indexName(index mongo.IndexModel) string {
+	if index.Options != nil && index.Options.Name != nil {
+		return *index.Options.Name
+	}
+
+	var parts []string
+	for _, key := range index.Keys.(bson.D) {
+		parts = append(parts, fmt.Sprintf("%s_%v", key.Key, key.Value))
+	}
+	return strings.Join(parts, "_")
+}
@@ -0,0 +1,99 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/backoff"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+)
+
+func TestIndexer(t *testing.T) {
+	client := testClient(t)
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	db := client.Database(fmt.Sprintf("events_test_indexer_%x", suffix))
+	t.Cleanup(func() { _ = db.Drop(context.Background()) })
+
+	indexer := NewIndexer(db, backoff.Backoff{Initial: time.Millisecond}, zap.NewNop())
+	indexer.Add(EventIndexes()...)
+	repo := RequireIndexes(NewEventRepository(db, nil), indexer)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, indexer.Check(ctx), repository.ErrUnavailable)
+	err := repo.Create(ctx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()})
+	assert.ErrorIs(t, err, repository.ErrUnavailable, "writes wait for the indexes")
+
+	indexer.Run(ctx)
+	status := indexer.Status()
+	assert.Equal(t, IndexesReady, status.State)
+	assert.Equal(t, 1, status.Attempts)
+	assert.NotNil(t, status.ReadyAt)
+	require.NoError(t, indexer.Check(ctx))
+	require.NoError(t, repo.Create(ctx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}))
+}
+
+func TestIndexerStopsOnConflicts(t *testing.T) {
+	client := testClient(t)
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	db := client.Database(fmt.Sprintf("events_test_conflict_%x", suffix))
+	t.Cleanup(func() { _ = db.Drop(context.Background()) })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The unique index cannot be built over keys stored twice.
+	_, err := db.Collection("api_keys").InsertMany(ctx, []any{bson.M{"hash": "h"}, bson.M{"hash": "h"}})
+	require.NoError(t, err)
+
+	indexer := NewIndexer(db, backoff.Backoff{Initial: time.Millisecond}, zap.NewNop())
+	indexer.Add(APIKeyIndexes("api_keys")...)
+	indexer.Run(ctx)
+
+	require.NoError(t, ctx.Err(), "the build was retried")
+	status := indexer.Status()
+	assert.Equal(t, IndexesFailed, status.State)
+	assert.Equal(t, 1, status.Attempts)
+	assert.NotEmpty(t, status.Error)
+	assert.ErrorIs(t, indexer.Ready(), repository.ErrUnavailable)
+}
+
+func TestIsPermanentIndexError(t *testing.T) {
+	assert.True(t, isPermanentIndexError(fmt.Errorf("create indexes of events: %w", mongo.CommandError{Code: duplicateKeyCode})))
+	assert.True(t, isPermanentIndexError(mongo.CommandError{Code: indexOptionsConflictCode}))
+	assert.True(t, isPermanentIndexError(mongo.CommandError{Code: indexKeySpecsConflictCode}))
+	assert.False(t, isPermanentIndexError(mongo.CommandError{Code: 189, Labels: []string{"RetryableWriteError"}}))
+	assert.False(t, isPermanentIndexError(topology.ServerSelectionError{Wrapped: topology.ErrServerSelectionTimeout}))
+	assert.False(t, isPermanentIndexError(nil))
+}
+
+func TestIndexerPending(t *testing.T) {
+	indexer := NewIndexer(nil, backoff.Backoff{Initial: time.Millisecond}, zap.NewNop())
+	assert.Equal(t, IndexesPending, indexer.Status().State)
+	assert.ErrorIs(t, indexer.Ready(), repository.ErrUnavailable)
+}
+
+func TestUnavailable(t *testing.T) {
+	selection := topology.ServerSelectionError{Wrapped: topology.ErrServerSelectionTimeout}
+	assert.ErrorIs(t, unavailable(selection), repository.ErrUnavailable)
+	assert.ErrorIs(t, unavailable(fmt.Errorf("find: %w", selection)), repository.ErrUnavailable)
+
+	canceled := topology.ServerSelectionError{Wrapped: context.Canceled}
+	assert.NotErrorIs(t, unavailable(canceled), repository.ErrUnavailable, "the caller gave up")
+
+	other := errors.New("bad query")
+	assert.Equal(t, other, unavailable(other))
+	assert.NoError(t, unavailable(nil))
+}
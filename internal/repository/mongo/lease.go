@@ -0,0 +1,113 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// locksCollection holds the leases of the background jobs that only one
+// instance may run at a time.
+const locksCollection = "locks"
+
+// ErrLeaseHeld is returned by Lease.Acquire while another instance holds
+// the lease.
+var ErrLeaseHeld = errors.New("the lease is held by another instance")
+
+// Lease is a lock that instances share through one document of a
+// collection. It expires when its holder stops renewing it, so an instance
+// that crashed does not keep it forever.
+type Lease struct {
+	coll  *mongo.Collection
+	id    string
+	owner string
+	ttl   time.Duration
+}
+
+// NewLease returns the lease stored as the document with id in coll. owner
+// names the instance that takes it and ttl is how long it lasts without
+// being renewed.
+func NewLease(coll *mongo.Collection, id, owner string, ttl time.Duration) *Lease {
+	return &Lease{coll: coll, id: id, owner: owner, ttl: ttl}
+}
+
+// Acquire takes the lease and renews it until release is called. Work done
+// under the lease must use the returned context: it is cancelled once the
+// lease is lost, because another instance took it over or it could not be
+// renewed before expiring.
+func (l *Lease) Acquire(ctx context.Context) (_ context.Context, release func() error, err error) {
+	expires, err := l.take(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leaseCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.renew(leaseCtx, cancel, expires)
+	}()
+
+	release = func() error {
+		cancel(nil)
+		<-done
+		_, err := l.coll.DeleteOne(context.WithoutCancel(ctx), bson.M{"_id": l.id, "owner": l.owner})
+		return unavailable(err)
+	}
+	return leaseCtx, release, nil
+}
+
+// take takes the lease when it is free, expired or already held by owner,
+// and returns when it expires. A lease held by someone else keeps the
+// filter from matching, so the upsert runs into the unique _id.
+func (l *Lease) take(ctx context.Context) (time.Time, error) {
+	now := time.Now().UTC()
+	expires := now.Add(l.ttl)
+	_, err := l.coll.UpdateOne(ctx,
+		bson.M{
+			"_id": l.id,
+			"$or": bson.A{
+				bson.M{"owner": l.owner},
+				bson.M{"expires_at": bson.M{"$lt": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"owner": l.owner, "expires_at": expires}},
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return time.Time{}, ErrLeaseHeld
+	}
+	return expires, unavailable(err)
+}
+
+// renew extends the lease every third of its ttl until ctx is done. A
+// failed renewal is retried at the next tick while the lease lasts; once it
+// would expire first, or another instance took it, cancel ends the work.
+func (l *Lease) renew(ctx context.Context, cancel context.CancelCauseFunc, expires time.Time) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := l.take(ctx)
+		if err == nil {
+			expires = renewed
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrLeaseHeld) || time.Until(expires) < l.ttl/3 {
+			cancel(fmt.Errorf("lost the lease %s: %w", l.id, err))
+			return
+		}
+	}
+}
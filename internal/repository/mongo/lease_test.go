@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLease(t *testing.T) {
+	db := testDatabase(t, testClient(t))
+	coll := db.Collection(locksCollection)
+	ctx := context.Background()
+
+	first := NewLease(coll, "job", "a", time.Minute)
+	second := NewLease(coll, "job", "b", time.Minute)
+
+	leaseCtx, release, err := first.Acquire(ctx)
+	require.NoError(t, err)
+	_, _, err = second.Acquire(ctx)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+	require.NoError(t, leaseCtx.Err())
+
+	require.NoError(t, release())
+	assert.Error(t, leaseCtx.Err(), "work under a released lease stops")
+	_, release, err = second.Acquire(ctx)
+	require.NoError(t, err, "a released lease is free")
+	require.NoError(t, release())
+
+	t.Run("an expired lease is taken over", func(t *testing.T) {
+		_, _, err := first.Acquire(ctx)
+		require.NoError(t, err)
+		_, err = coll.UpdateOne(ctx, bson.M{"_id": "job"}, bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Second)}})
+		require.NoError(t, err)
+
+		_, release, err := second.Acquire(ctx)
+		require.NoError(t, err)
+		require.NoError(t, release())
+	})
+
+	t.Run("a lost lease cancels the work", func(t *testing.T) {
+		short := NewLease(coll, "short", "a", 300*time.Millisecond)
+		leaseCtx, release, err := short.Acquire(ctx)
+		require.NoError(t, err)
+		defer func() { _ = release() }()
+
+		_, err = coll.UpdateOne(ctx, bson.M{"_id": "short"}, bson.M{"$set": bson.M{"owner": "b"}})
+		require.NoError(t, err)
+
+		select {
+		case <-leaseCtx.Done():
+			assert.ErrorIs(t, context.Cause(leaseCtx), ErrLeaseHeld)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the lease was not given up")
+		}
+	})
+}
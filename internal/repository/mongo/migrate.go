@@ -0,0 +1,154 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const migrationsCollection = "schema_migrations"
+
+// migrationLockID is the document of schema_migrations that the instance
+// migrating holds; the applied migrations have their version as ID.
+const migrationLockID = "lock"
+
+// migrationLease is how long the lock outlives an instance that stopped
+// without releasing it. The holder renews it while migrating and stops
+// once it cannot.
+var migrationLease = time.Minute
+
+// ErrMigrationLocked is returned by Migrate while another instance migrates.
+var ErrMigrationLocked = errors.New("another instance is migrating the schema")
+
+// Migration is one numbered change of the documents written by older
+// versions of the service. MongoDB cannot roll back a change of many
+// documents, so Apply must be safe to run again after it failed halfway.
+type Migration struct {
+	Version int
+	Name    string
+	// Pending counts the documents Apply would change.
+	Pending func(ctx context.Context, db *mongo.Database) (int64, error)
+	// Apply changes them and returns how many it changed.
+	Apply func(ctx context.Context, db *mongo.Database) (int64, error)
+}
+
+// MigrationResult is a migration that was applied, or would be in a dry
+// run, with the number of documents it changed.
+type MigrationResult struct {
+	Version   int
+	Name      string
+	Documents int64
+}
+
+type migrationRecord struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	Documents int64     `bson:"documents"`
+	AppliedAt time.Time `bson:"applied_at"`
+	AppliedBy string    `bson:"applied_by"`
+}
+
+// Migrate applies the migrations that schema_migrations does not list yet,
+// in the order of their versions, and records each one once it is done.
+// owner names the instance in the lock that keeps other instances from
+// migrating at the same time; they get ErrMigrationLocked. A run that loses
+// the lock stops before the next migration, failing with the reason. With
+// dryRun nothing is changed or locked, and the results tell how many
+// documents every pending migration would change.
+func Migrate(ctx context.Context, db *mongo.Database, owner string, dryRun bool) ([]MigrationResult, error) {
+	return migrate(ctx, db, migrations, owner, dryRun)
+}
+
+func migrate(ctx context.Context, db *mongo.Database, all []Migration, owner string, dryRun bool) (_ []MigrationResult, err error) {
+	coll := db.Collection(migrationsCollection)
+
+	if !dryRun {
+		lockCtx, release, lockErr := NewLease(coll, migrationLockID, owner, migrationLease).Acquire(ctx)
+		if errors.Is(lockErr, ErrLeaseHeld) {
+			return nil, ErrMigrationLocked
+		}
+		if lockErr != nil {
+			return nil, lockErr
+		}
+		defer func() { err = errors.Join(err, release()) }()
+		// Everything below is cancelled once the lock is lost.
+		ctx = lockCtx
+	}
+
+	// Read after locking, since another instance may just have finished.
+	applied, err := appliedMigrations(ctx, coll)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []MigrationResult
+	for _, m := range pendingMigrations(all, applied) {
+		if dryRun {
+			n, err := m.Pending(ctx, db)
+			if err != nil {
+				return results, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, unavailable(err))
+			}
+			results = append(results, MigrationResult{Version: m.Version, Name: m.Name, Documents: n})
+			continue
+		}
+
+		// Another instance may have taken over while the last one ran.
+		if ctx.Err() != nil {
+			return results, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, context.Cause(ctx))
+		}
+		n, err := m.Apply(ctx, db)
+		if err != nil && ctx.Err() != nil {
+			err = context.Cause(ctx)
+		}
+		if err != nil {
+			return results, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, unavailable(err))
+		}
+		_, err = coll.InsertOne(ctx, migrationRecord{
+			Version:   m.Version,
+			Name:      m.Name,
+			Documents: n,
+			AppliedAt: time.Now().UTC(),
+			AppliedBy: owner,
+		})
+		if err != nil {
+			return results, fmt.Errorf("migration %d %s: %w", m.Version, m.Name, unavailable(err))
+		}
+		results = append(results, MigrationResult{Version: m.Version, Name: m.Name, Documents: n})
+	}
+	return results, nil
+}
+
+func appliedMigrations(ctx context.Context, coll *mongo.Collection) (map[int]bool, error) {
+	cursor, err := coll.Find(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, unavailable(err)
+	}
+
+	applied := make(map[int]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+	return applied, nil
+}
+
+func pendingMigrations(all []Migration, applied map[int]bool) []Migration {
+	var pending []Migration
+	for _, m := range all {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+	return pending
+}
@@ -0,0 +1,224 @@
+package mongo
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "migration %s", m.Name)
+		assert.NotEmpty(t, m.Name)
+		assert.NotNil(t, m.Pending, "migration %s", m.Name)
+		assert.NotNil(t, m.Apply, "migration %s", m.Name)
+	}
+}
+
+func TestMigrateBackfillsEventVersion(t *testing.T) {
+	db := testDatabase(t, testClient(t))
+	ctx := tenant.WithAllTenants(context.Background())
+
+	legacy := []any{
+		bson.M{"_id": primitive.NewObjectID(), "tenant": tenant.Default, "type": "deploy",
+			"state": model.EventStateStarted, "started_at": time.Now()},
+		bson.M{"_id": primitive.NewObjectID(), "tenant": tenant.Default, "type": "backup",
+			"state": model.EventStateFinished, "started_at": time.Now(), "finished_at": time.Now()},
+	}
+	_, err := db.Collection("events").InsertMany(ctx, legacy)
+	require.NoError(t, err)
+
+	results, err := Migrate(ctx, db, "test", true)
+	require.NoError(t, err)
+	require.Len(t, results, len(migrations))
+	assert.Equal(t, MigrationResult{Version: 1, Name: "backfill_event_version", Documents: 2}, results[0])
+	count, err := db.Collection("events").CountDocuments(ctx, missingVersion)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count, "a dry run changes nothing")
+
+	results, err = Migrate(ctx, db, "test", false)
+	require.NoError(t, err)
+	assert.Equal(t, []MigrationResult{
+		{Version: 1, Name: "backfill_event_version", Documents: 2},
+		{Version: 2, Name: "assign_default_tenant"},
+		{Version: 3, Name: "finish_duplicate_events"},
+	}, results)
+
+	repo := NewEventRepository(db, nil)
+	events, err := repo.List(ctx, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for _, event := range events {
+		if event.State == model.EventStateStarted {
+			assert.EqualValues(t, 1, event.Version)
+			finished := time.Now()
+			event.State = model.EventStateFinished
+			event.FinishedAt = &finished
+			require.NoError(t, repo.Update(ctx, &event), "backfilled events can be updated")
+		} else {
+			assert.EqualValues(t, 2, event.Version)
+		}
+	}
+
+	results, err = Migrate(ctx, db, "test", false)
+	require.NoError(t, err)
+	assert.Empty(t, results, "applied migrations are not applied again")
+	lock, err := db.Collection(migrationsCollection).CountDocuments(ctx, bson.M{"_id": migrationLockID})
+	require.NoError(t, err)
+	assert.Zero(t, lock, "the lock is released")
+}
+
+func TestMigrateLocked(t *testing.T) {
+	db := testDatabase(t, testClient(t))
+	ctx := context.Background()
+
+	applied := 0
+	all := []Migration{{
+		Version: 1,
+		Name:    "noop",
+		Pending: func(context.Context, *mongo.Database) (int64, error) { return 0, nil },
+		Apply: func(context.Context, *mongo.Database) (int64, error) {
+			applied++
+			return 0, nil
+		},
+	}}
+
+	coll := db.Collection(migrationsCollection)
+	_, err := NewLease(coll, migrationLockID, "other", migrationLease).take(ctx)
+	require.NoError(t, err)
+	_, err = migrate(ctx, db, all, "test", false)
+	assert.ErrorIs(t, err, ErrMigrationLocked)
+	assert.Zero(t, applied)
+
+	results, err := migrate(ctx, db, all, "test", true)
+	require.NoError(t, err, "dry runs do not lock")
+	assert.Len(t, results, 1)
+
+	_, err = coll.UpdateByID(ctx, migrationLockID, bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Second)}})
+	require.NoError(t, err)
+	_, err = migrate(ctx, db, all, "test", false)
+	require.NoError(t, err, "an expired lock is taken over")
+	assert.Equal(t, 1, applied)
+}
+
+func TestMigrateStopsWhenTheLockIsLost(t *testing.T) {
+	db := testDatabase(t, testClient(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer func(lease time.Duration) { migrationLease = lease }(migrationLease)
+	migrationLease = 300 * time.Millisecond
+
+	coll := db.Collection(migrationsCollection)
+	none := func(context.Context, *mongo.Database) (int64, error) { return 0, nil }
+	applied := 0
+	all := []Migration{
+		{
+			Version: 1,
+			Name:    "taken_over",
+			Pending: none,
+			Apply: func(ctx context.Context, _ *mongo.Database) (int64, error) {
+				// Another instance takes the lock over while this one runs.
+				_, err := coll.UpdateByID(context.Background(), migrationLockID,
+					bson.M{"$set": bson.M{"owner": "other", "expires_at": time.Now().Add(time.Hour)}})
+				require.NoError(t, err)
+				<-ctx.Done()
+				return 0, ctx.Err()
+			},
+		},
+		{
+			Version: 2,
+			Name:    "noop",
+			Pending: none,
+			Apply: func(context.Context, *mongo.Database) (int64, error) {
+				applied++
+				return 0, nil
+			},
+		},
+	}
+
+	_, err := migrate(ctx, db, all, "test", false)
+	require.NoError(t, ctx.Err(), "the run went on without the lock")
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+	assert.Zero(t, applied)
+
+	recorded, err := coll.CountDocuments(ctx, bson.M{"_id": bson.M{"$type": "number"}})
+	require.NoError(t, err)
+	assert.Zero(t, recorded)
+	var lock struct {
+		Owner string `bson:"owner"`
+	}
+	require.NoError(t, coll.FindOne(ctx, bson.M{"_id": migrationLockID}).Decode(&lock))
+	assert.Equal(t, "other", lock.Owner, "the lock of the other instance is kept")
+}
+
+func TestMigrateFixesLegacyEvents(t *testing.T) {
+	client := testClient(t)
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	// Without the indexes, which legacy events predate.
+	db := client.Database(fmt.Sprintf("events_test_legacy_%x", suffix))
+	t.Cleanup(func() { _ = db.Drop(context.Background()) })
+	ctx := context.Background()
+	events := db.Collection("events")
+
+	_, err := events.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "type", Value: 1}, {Key: "state", Value: 1}}})
+	require.NoError(t, err)
+	start := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	_, err = events.InsertMany(ctx, []any{
+		bson.M{"type": "deploy", "state": model.EventStateStarted, "started_at": start, "version": 1},
+		bson.M{"type": "deploy", "state": model.EventStateStarted, "started_at": start.Add(time.Minute), "version": 1},
+		bson.M{"type": "deploy", "state": model.EventStateStarted, "started_at": start.Add(2 * time.Minute)},
+		bson.M{"tenant": "team-b", "type": "deploy", "state": model.EventStateStarted, "started_at": start, "version": 1},
+	})
+	require.NoError(t, err)
+
+	results, err := Migrate(ctx, db, "test", false)
+	require.NoError(t, err)
+	assert.Equal(t, []MigrationResult{
+		{Version: 1, Name: "backfill_event_version", Documents: 1},
+		{Version: 2, Name: "assign_default_tenant", Documents: 3},
+		{Version: 3, Name: "finish_duplicate_events", Documents: 2},
+	}, results)
+	require.NoError(t, EnsureIndexes(ctx, db, EventIndexes()...), "the unique index is built over the fixed events")
+
+	var running []model.Event
+	cursor, err := events.Find(ctx, bson.M{"state": model.EventStateStarted})
+	require.NoError(t, err)
+	require.NoError(t, cursor.All(ctx, &running))
+	require.Len(t, running, 2, "one unfinished deploy per tenant is left")
+	for _, event := range running {
+		if event.Tenant == tenant.Default {
+			assert.True(t, start.Add(2*time.Minute).Equal(event.StartedAt), "the latest start is kept")
+		}
+	}
+
+	var finished []model.Event
+	cursor, err = events.Find(ctx, bson.M{"state": model.EventStateFinished})
+	require.NoError(t, err)
+	require.NoError(t, cursor.All(ctx, &finished))
+	require.Len(t, finished, 2)
+	for _, event := range finished {
+		assert.Equal(t, tenant.Default, event.Tenant)
+		assert.Equal(t, int64(2), event.Version)
+		require.NotNil(t, event.FinishedAt)
+		assert.True(t, start.Add(2*time.Minute).Equal(*event.FinishedAt), "finished when the latest one started")
+	}
+
+	specs, err := events.Indexes().ListSpecifications(ctx)
+	require.NoError(t, err)
+	for _, spec := range specs {
+		assert.NotEqual(t, "type_1_state_1", spec.Name, "the superseded index is dropped")
+	}
+	require.NoError(t, EnsureIndexes(ctx, db, EventIndexes()...), "dropping an index that is gone is not an error")
+}
@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// migrations are applied by Migrate in the order of their versions. Add new
+// ones at the end with the next version; never renumber or remove one that
+// was released.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "backfill_event_version",
+		Pending: countEvents(missingVersion),
+		Apply:   backfillEventVersion,
+	},
+	{
+		Version: 2,
+		Name:    "assign_default_tenant",
+		Pending: countEvents(missingTenant),
+		Apply:   assignDefaultTenant,
+	},
+	{
+		Version: 3,
+		Name:    "finish_duplicate_events",
+		Pending: countDuplicates,
+		Apply:   finishDuplicates,
+	},
+}
+
+// missingVersion matches events stored before optimistic locking, which
+// Update can never match since it filters on the version.
+var missingVersion = bson.M{"version": bson.M{"$exists": false}}
+
+func countEvents(filter bson.M) func(ctx context.Context, db *mongo.Database) (int64, error) {
+	return func(ctx context.Context, db *mongo.Database) (int64, error) {
+		return db.Collection("events").CountDocuments(ctx, filter)
+	}
+}
+
+// backfillEventVersion gives started events version 1 and finished ones
+// version 2, the versions Create and Update would have given them.
+func backfillEventVersion(ctx context.Context, db *mongo.Database) (int64, error) {
+	result, err := db.Collection("events").UpdateMany(ctx, missingVersion, bson.A{
+		bson.M{"$set": bson.M{"version": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$state", model.EventStateStarted}}, int64(1), int64(2),
+		}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// missingTenant matches events stored before tenants were introduced.
+var missingTenant = bson.M{"tenant": bson.M{"$exists": false}}
+
+// assignDefaultTenant assigns events stored before tenants were introduced
+// to the default tenant. It must run before finishDuplicates, which groups
+// events by tenant.
+func assignDefaultTenant(ctx context.Context, db *mongo.Database) (int64, error) {
+	result, err := db.Collection("events").UpdateMany(ctx, missingTenant,
+		bson.M{"$set": bson.M{"tenant": tenant.Default}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// duplicatesPipeline finds the tenants and types with more than one
+// unfinished event, with their IDs oldest first and the latest start.
+var duplicatesPipeline = mongo.Pipeline{
+	{{Key: "$match", Value: bson.M{"state": model.EventStateStarted}}},
+	{{Key: "$sort", Value: bson.D{{Key: "started_at", Value: 1}, {Key: "_id", Value: 1}}}},
+	{{Key: "$group", Value: bson.M{
+		"_id":       bson.M{"tenant": "$tenant", "type": "$type"},
+		"ids":       bson.M{"$push": "$_id"},
+		"latest_at": bson.M{"$last": "$started_at"},
+	}}},
+	{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+}
+
+type duplicateGroup struct {
+	IDs      []primitive.ObjectID `bson:"ids"`
+	LatestAt time.Time            `bson:"latest_at"`
+}
+
+func findDuplicates(ctx context.Context, db *mongo.Database) ([]duplicateGroup, error) {
+	cursor, err := db.Collection("events").Aggregate(ctx, duplicatesPipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []duplicateGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// countDuplicates counts the events finishDuplicates would finish.
+func countDuplicates(ctx context.Context, db *mongo.Database) (int64, error) {
+	groups, err := findDuplicates(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, g := range groups {
+		n += int64(len(g.IDs) - 1)
+	}
+	return n, nil
+}
+
+// finishDuplicates keeps the latest unfinished event of every tenant and
+// type and finishes the others when the latest one started. Servers without
+// the unique index could start a type twice, and the index cannot be built
+// over such duplicates.
+func finishDuplicates(ctx context.Context, db *mongo.Database) (int64, error) {
+	groups, err := findDuplicates(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	var finished int64
+	for _, g := range groups {
+		older := g.IDs[:len(g.IDs)-1]
+		result, err := db.Collection("events").UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": older}, "state": model.EventStateStarted},
+			bson.A{bson.M{"$set": bson.M{
+				"state":       model.EventStateFinished,
+				"finished_at": g.LatestAt,
+				"version":     bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", int64(1)}}, int64(1)}},
+			}}})
+		if err != nil {
+			return finished, err
+		}
+		finished += result.ModifiedCount
+	}
+	return finished, nil
+}
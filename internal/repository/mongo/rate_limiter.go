@@ -0,0 +1,75 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/godev/events-service/internal/ratelimit"
+)
+
+var rateLimitIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	},
+}
+
+type RateLimiter struct {
+	collection *mongo.Collection
+}
+
+// NewRateLimiter keeps the token buckets in the named collection so that
+// all replicas share them. Buckets expire once they have refilled, through
+// the TTL index of RateLimitIndexes.
+func NewRateLimiter(db *mongo.Database, collection string) ratelimit.Limiter {
+	return &RateLimiter{collection: db.Collection(collection)}
+}
+
+type rateLimitBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	burst := float64(limit.Burst)
+	// The bucket is refilled and taken from in a single update, using the
+	// server clock so that replicas with skewed clocks agree.
+	elapsed := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updated_at", "$$NOW"}}}},
+		1000,
+	}}
+	refilled := bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", burst}},
+		bson.M{"$multiply": bson.A{elapsed, limit.Rate}},
+	}}}}
+	hasToken := bson.M{"$gte": bson.A{"$tokens", 1}}
+	refillMs := bson.M{"$multiply": bson.A{
+		bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{burst, "$tokens"}}, limit.Rate}},
+		1000,
+	}}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled, "updated_at": "$$NOW"}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": hasToken,
+			"tokens":  bson.M{"$cond": bson.A{hasToken, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+		}}},
+		{{Key: "$set", Value: bson.M{"expires_at": bson.M{"$add": bson.A{"$$NOW", refillMs}}}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var b rateLimitBucket
+	err := l.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&b)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another replica created the bucket concurrently; it exists now.
+		err = l.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&b)
+	}
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return ratelimit.NewResult(limit, b.Tokens, b.Allowed), nil
+}
@@ -0,0 +1,34 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/internal/ratelimit"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(testDatabase(t, testClient(t)), "rate_limits")
+	limit := ratelimit.Limit{Rate: 0.01, Burst: 2}
+	ctx := context.Background()
+
+	for _, remaining := range []int{1, 0} {
+		result, err := limiter.Allow(ctx, "a", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := limiter.Allow(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, 100*time.Second, result.RetryAfter, float64(time.Second))
+
+	result, err = limiter.Allow(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,152 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/retention"
+)
+
+const archiveCollection = "events_archive"
+
+const duplicateKeyCode = 11000
+
+var retentionIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "state", Value: 1},
+			{Key: "type", Value: 1},
+			{Key: "finished_at", Value: 1},
+		},
+	},
+}
+
+type RetentionStore struct {
+	db     *mongo.Database
+	events *mongo.Collection
+}
+
+// NewRetentionStore finds expired events in the events collection. Deleting
+// them also deletes their history and event log, so that a rebuild cannot
+// bring them back; the audit log is kept. Its indexes are those of
+// RetentionIndexes.
+func NewRetentionStore(db *mongo.Database) retention.Store {
+	return &RetentionStore{db: db, events: db.Collection("events")}
+}
+
+func expiredFilter(rule retention.Rule) bson.M {
+	filter := bson.M{
+		"state":       model.EventStateFinished,
+		"finished_at": bson.M{"$lt": rule.Before},
+	}
+	if rule.Type != "" {
+		filter["type"] = rule.Type
+	} else if len(rule.Exclude) > 0 {
+		filter["type"] = bson.M{"$nin": rule.Exclude}
+	}
+	return filter
+}
+
+func (s *RetentionStore) Expired(ctx context.Context, rule retention.Rule, limit int64) ([]model.Event, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "finished_at", Value: 1}}).
+		SetLimit(limit)
+
+	cursor, err := s.events.Find(ctx, expiredFilter(rule), opts)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []model.Event
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, unavailable(err)
+	}
+
+	return events, nil
+}
+
+func (s *RetentionStore) CountExpired(ctx context.Context, rule retention.Rule) (int64, error) {
+	n, err := s.events.CountDocuments(ctx, expiredFilter(rule))
+	return n, unavailable(err)
+}
+
+// Delete removes the log and history of the events before the events
+// themselves. A delete that fails halfway leaves events that the next purge
+// finds again, never a log that a rebuild would restore them from.
+func (s *RetentionStore) Delete(ctx context.Context, ids []primitive.ObjectID) error {
+	derived := bson.M{"event_id": bson.M{"$in": ids}}
+	for _, name := range []string{logCollection, historyCollection} {
+		if _, err := s.db.Collection(name).DeleteMany(ctx, derived); err != nil {
+			return unavailable(err)
+		}
+	}
+
+	_, err := s.events.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return unavailable(err)
+}
+
+// retentionLease is how long the retention lock outlives an instance that
+// stopped without releasing it.
+const retentionLease = time.Minute
+
+type retentionLock struct {
+	lease *Lease
+}
+
+// NewRetentionLock lets one instance at a time purge expired events. The
+// lock is a lease in the locks collection that owner renews while purging.
+func NewRetentionLock(db *mongo.Database, owner string) retention.Lock {
+	return retentionLock{lease: NewLease(db.Collection(locksCollection), "retention", owner, retentionLease)}
+}
+
+func (l retentionLock) Acquire(ctx context.Context) (context.Context, func() error, error) {
+	ctx, release, err := l.lease.Acquire(ctx)
+	if errors.Is(err, ErrLeaseHeld) {
+		return nil, nil, retention.ErrLocked
+	}
+	return ctx, release, err
+}
+
+type collectionArchiver struct {
+	collection *mongo.Collection
+}
+
+// NewCollectionArchiver copies events to the events_archive collection.
+func NewCollectionArchiver(db *mongo.Database) retention.Archiver {
+	return &collectionArchiver{collection: db.Collection(archiveCollection)}
+}
+
+func (a *collectionArchiver) Archive(ctx context.Context, events []model.Event) error {
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		docs[i] = events[i]
+	}
+
+	// Events archived by an earlier, interrupted run are already there.
+	_, err := a.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeys(err) {
+		return unavailable(err)
+	}
+	return nil
+}
+
+func onlyDuplicateKeys(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != duplicateKeyCode {
+			return false
+		}
+	}
+	return true
+}
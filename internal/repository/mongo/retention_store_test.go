@@ -0,0 +1,27 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/repository/repotest"
+)
+
+func TestRetentionStore(t *testing.T) {
+	client := testClient(t)
+
+	repotest.RunRetention(t, func(t *testing.T) repotest.Retention {
+		db := testDatabase(t, client)
+		return repotest.Retention{
+			Events:   NewEventRepository(db, nil),
+			Store:    NewRetentionStore(db),
+			Archiver: NewCollectionArchiver(db),
+			Archived: func(ctx context.Context, id primitive.ObjectID) (int64, error) {
+				return db.Collection(archiveCollection).CountDocuments(ctx, bson.M{"_id": id})
+			},
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewAuditRepository reads the audit log that EventRepository writes; the
+// table is created by the migrations NewEventRepository runs.
+func NewAuditRepository(pool *pgxpool.Pool) repository.IAuditRepository {
+	return &AuditRepository{pool: pool}
+}
+
+func insertAudit(ctx context.Context, pool *pgxpool.Pool, entry *model.AuditEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+
+	var before *int
+	if entry.Before != nil {
+		state := int(*entry.Before)
+		before = &state
+	}
+
+	_, err := conn(ctx, pool).Exec(ctx, `
+		INSERT INTO audit (id, tenant, actor, action, event_id, event_type, state_before,
+			state_after, request_id, source_ip, at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		entry.ID.Hex(), entry.Tenant, entry.Actor, string(entry.Action), entry.EventID.Hex(), entry.EventType,
+		before, int(entry.After), entry.RequestID, entry.SourceIP, entry.At)
+	return err
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter model.AuditFilter) (_ []model.AuditEntry, err error) {
+	ctx, span := tracing.Start(ctx, "AuditRepository.List",
+		attribute.String("audit.actor", filter.Actor),
+		attribute.String("audit.action", string(filter.Action)))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	if filter.Actor != "" {
+		cond.add("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		cond.add("action = ?", string(filter.Action))
+	}
+	if !filter.EventID.IsZero() {
+		cond.add("event_id = ?", filter.EventID.Hex())
+	}
+	if filter.EventType != "" {
+		cond.add("event_type = ?", filter.EventType)
+	}
+	if !filter.From.IsZero() {
+		cond.add("at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		cond.add("at < ?", filter.To)
+	}
+	where := cond.where()
+	page := ` LIMIT ` + cond.arg(limitOf(filter.Limit)) + ` OFFSET ` + cond.arg(filter.Offset)
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, tenant, actor, action, event_id, event_type, state_before, state_after,
+			request_id, source_ip, at
+		FROM audit`+where+`
+		ORDER BY at DESC, seq DESC`+page, cond.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var (
+			entry   model.AuditEntry
+			entryID string
+			action  string
+			eventID string
+			before  *int
+			after   int
+		)
+		err := rows.Scan(&entryID, &entry.Tenant, &entry.Actor, &action, &eventID, &entry.EventType,
+			&before, &after, &entry.RequestID, &entry.SourceIP, &entry.At)
+		if err != nil {
+			return nil, err
+		}
+		if entry.ID, err = primitive.ObjectIDFromHex(entryID); err != nil {
+			return nil, err
+		}
+		if entry.EventID, err = primitive.ObjectIDFromHex(eventID); err != nil {
+			return nil, err
+		}
+		entry.Action = model.AuditAction(action)
+		if before != nil {
+			state := model.EventState(*before)
+			entry.Before = &state
+		}
+		entry.After = model.EventState(after)
+		entry.At = entry.At.UTC()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,15 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/repository/repotest"
+)
+
+func TestAuditRepository(t *testing.T) {
+	repotest.RunAudit(t, func(t *testing.T) (repository.IEventRepository, repository.IAuditRepository) {
+		pool := testPool(t)
+		return testRepository(t, pool), NewAuditRepository(pool)
+	})
+}
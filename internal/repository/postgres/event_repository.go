@@ -0,0 +1,408 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tenant"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+const eventColumns = "id, tenant, type, state, started_at, finished_at, version"
+
+// changesChannel is the NOTIFY channel that carries every created or
+// updated event to Watch.
+const changesChannel = "events_changed"
+
+// uniqueViolation is the SQLSTATE of a unique constraint failure.
+const uniqueViolation = "23505"
+
+type EventRepository struct {
+	pool     *pgxpool.Pool
+	listener *listener
+}
+
+// NewEventRepository stores events in the database of pool, migrating its
+// schema first.
+func NewEventRepository(pool *pgxpool.Pool) (repository.IEventRepository, error) {
+	if err := Migrate(context.Background(), pool); err != nil {
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+
+	return &EventRepository{
+		pool:     pool,
+		listener: newListener(pool),
+	}, nil
+}
+
+// querier is what *pgxpool.Pool and pgx.Tx have in common.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+// conn returns the transaction of ctx, or pool outside of one.
+func conn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
+
+// outsideTx returns ctx without its transaction, so that what is written
+// with it stays when the transaction rolls back.
+func outsideTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txKey{}, nil)
+}
+
+// conditions builds the WHERE clause and numbered arguments of a query.
+type conditions struct {
+	clauses []string
+	args    []any
+}
+
+// scoped starts conditions that restrict a query to the tenant of ctx.
+func scoped(ctx context.Context) *conditions {
+	c := &conditions{}
+	if !tenant.All(ctx) {
+		c.add("tenant = ?", tenant.FromContext(ctx))
+	}
+	return c
+}
+
+// arg adds an argument and returns its placeholder.
+func (c *conditions) arg(val any) string {
+	c.args = append(c.args, val)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+// add adds a clause whose "?" stands for val.
+func (c *conditions) add(clause string, val any) {
+	c.clauses = append(c.clauses, strings.Replace(clause, "?", c.arg(val), 1))
+}
+
+func (c *conditions) where() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// limitOf turns a limit of zero, which means no limit, into NULL.
+func limitOf(limit int64) *int64 {
+	if limit <= 0 {
+		return nil
+	}
+	return &limit
+}
+
+func scanEvent(row pgx.Row) (model.Event, error) {
+	var (
+		event      model.Event
+		id         string
+		state      int
+		finishedAt *time.Time
+	)
+	err := row.Scan(&id, &event.Tenant, &event.Type, &state, &event.StartedAt, &finishedAt, &event.Version)
+	if err != nil {
+		return event, err
+	}
+
+	if event.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return event, err
+	}
+	event.State = model.EventState(state)
+	event.StartedAt = event.StartedAt.UTC()
+	if finishedAt != nil {
+		utc := finishedAt.UTC()
+		event.FinishedAt = &utc
+	}
+	return event, nil
+}
+
+func scanEvents(rows pgx.Rows) ([]model.Event, error) {
+	defer rows.Close()
+
+	var events []model.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// notify tells watchers about event once the transaction of ctx commits.
+func notify(ctx context.Context, pool *pgxpool.Pool, event model.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, pool).Exec(ctx, `SELECT pg_notify($1, $2)`, changesChannel, string(payload))
+	return err
+}
+
+func (r *EventRepository) Create(ctx context.Context, event *model.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Create", attribute.String("event.type", event.Type))
+	defer func() { tracing.End(span, err) }()
+
+	if !tenant.All(ctx) || event.Tenant == "" {
+		event.Tenant = tenant.FromContext(ctx)
+	}
+
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+
+	return r.WithTransaction(ctx, func(txCtx context.Context) error {
+		event.Version = 1
+		_, err := conn(txCtx, r.pool).Exec(txCtx,
+			`INSERT INTO events (`+eventColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			event.ID.Hex(), event.Tenant, event.Type, int(event.State),
+			event.StartedAt, event.FinishedAt, event.Version)
+		if isUniqueViolation(err) {
+			return repository.ErrUnfinishedExists
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := insertAudit(txCtx, r.pool, repository.NewAuditEntry(txCtx, model.AuditActionStart, event, nil)); err != nil {
+			return err
+		}
+		if err := insertHistory(txCtx, r.pool, repository.NewHistoryEntry(txCtx, model.TransitionStarted, *event)); err != nil {
+			return err
+		}
+
+		return notify(txCtx, r.pool, *event)
+	})
+}
+
+func (r *EventRepository) FindUnfinishedByType(ctx context.Context, eventType, _ string) (_ *model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.FindUnfinishedByType", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	cond.add("type = ?", eventType)
+	cond.add("state = ?", int(model.EventStateStarted))
+	return r.findOne(ctx, cond, "")
+}
+
+func (r *EventRepository) FindByID(ctx context.Context, id primitive.ObjectID) (_ *model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.FindByID", attribute.String("event.id", id.Hex()))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	cond.add("id = ?", id.Hex())
+	return r.findOne(ctx, cond, "")
+}
+
+// findOne returns the event that matches cond, or nil when there is none.
+// lock is appended to the query, e.g. " FOR UPDATE".
+func (r *EventRepository) findOne(ctx context.Context, cond *conditions, lock string) (*model.Event, error) {
+	row := conn(ctx, r.pool).QueryRow(ctx, `SELECT `+eventColumns+` FROM events`+cond.where()+lock, cond.args...)
+	event, err := scanEvent(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *EventRepository) Update(ctx context.Context, event *model.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Update",
+		attribute.String("event.id", event.ID.Hex()),
+		attribute.String("event.type", event.Type),
+		attribute.Int64("event.version", event.Version))
+	defer func() { tracing.End(span, err) }()
+
+	// current is the stored event when the update loses a version race.
+	var current *model.Event
+	err = r.WithTransaction(ctx, func(txCtx context.Context) error {
+		current = nil
+		cond := scoped(txCtx)
+		cond.add("id = ?", event.ID.Hex())
+
+		// The row stays locked until the transaction ends, so the version
+		// cannot change between the check and the update. The previous
+		// state of the event goes into the audit entry.
+		before, err := r.findOne(txCtx, cond, " FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		if before == nil || before.Version != event.Version {
+			current = before
+			return repository.ErrVersionConflict
+		}
+
+		cond.add("version = ?", event.Version)
+		set := "state = " + cond.arg(int(event.State)) +
+			", finished_at = " + cond.arg(event.FinishedAt) +
+			", version = " + cond.arg(event.Version+1)
+		tag, err := conn(txCtx, r.pool).Exec(txCtx, `UPDATE events SET `+set+cond.where(), cond.args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			current = before
+			return repository.ErrVersionConflict
+		}
+
+		entry := repository.NewAuditEntry(txCtx, repository.ChangeAction(before.State, event.State), event, &before.State)
+		entry.Tenant = before.Tenant
+		if err := insertAudit(txCtx, r.pool, entry); err != nil {
+			return err
+		}
+
+		after := *before
+		after.State = event.State
+		after.FinishedAt = event.FinishedAt
+		after.Version = event.Version + 1
+		change := repository.ChangeTransition(before.State, after.State)
+		if err := insertHistory(txCtx, r.pool, repository.NewHistoryEntry(txCtx, change, after)); err != nil {
+			return err
+		}
+
+		return notify(txCtx, r.pool, after)
+	})
+
+	// The conflict is recorded even when an outer transaction, such as a
+	// batch, is rolled back because of it.
+	if errors.Is(err, repository.ErrVersionConflict) && current != nil {
+		if herr := insertHistory(outsideTx(ctx), r.pool, repository.NewConflictEntry(ctx, *event, current)); herr != nil {
+			err = errors.Join(err, herr)
+		}
+	}
+	return err
+}
+
+func (r *EventRepository) List(ctx context.Context, eventType string, offset, limit int64) (_ []model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.List", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	if eventType != "" {
+		cond.add("type = ?", eventType)
+	}
+	where := cond.where()
+	page := ` LIMIT ` + cond.arg(limitOf(limit)) + ` OFFSET ` + cond.arg(offset)
+
+	rows, err := conn(ctx, r.pool).Query(ctx,
+		`SELECT `+eventColumns+` FROM events`+where+` ORDER BY started_at DESC`+page, cond.args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (r *EventRepository) Stats(ctx context.Context, eventType string) (_ []model.EventStats, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Stats", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	if eventType != "" {
+		cond.add("type = ?", eventType)
+	}
+	where := cond.where()
+	started := cond.arg(int(model.EventStateStarted))
+	finished := cond.arg(int(model.EventStateFinished))
+
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT tenant, type,
+			COUNT(*) FILTER (WHERE state = `+started+`),
+			COUNT(*) FILTER (WHERE state = `+finished+`),
+			COALESCE(AVG(EXTRACT(EPOCH FROM finished_at - started_at) * 1000)
+				FILTER (WHERE state = `+finished+`), 0)::float8,
+			MAX(started_at)
+		FROM events`+where+`
+		GROUP BY tenant, type
+		ORDER BY tenant, type`, cond.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []model.EventStats
+	for rows.Next() {
+		var s model.EventStats
+		if err := rows.Scan(&s.Tenant, &s.Type, &s.Running, &s.Finished, &s.AvgDurationMs, &s.LastStartedAt); err != nil {
+			return nil, err
+		}
+		s.LastStartedAt = s.LastStartedAt.UTC()
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// WithTransaction runs fn inside a PostgreSQL transaction. When ctx already
+// carries a transaction (e.g. a batch of operations), fn runs in a
+// savepoint of it: an error aborts a PostgreSQL transaction, so without one
+// a failure the caller recovers from, such as ErrUnfinishedExists, would
+// fail the rest of it. Only the savepoint is rolled back when fn fails.
+func (r *EventRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	var db interface {
+		Begin(ctx context.Context) (pgx.Tx, error)
+	} = r.pool
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		db = tx
+	}
+
+	ctx, span := tracing.Start(ctx, "EventRepository.WithTransaction")
+	defer func() { tracing.End(span, err) }()
+
+	return pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Watch calls fn with every event that is created or updated until ctx is
+// done or fn fails. Every committed change sends a notification; one
+// connection listens for them on behalf of all watchers.
+func (r *EventRepository) Watch(ctx context.Context, eventType string, fn func(event model.Event) error) error {
+	w := r.listener.subscribe()
+	defer r.listener.unsubscribe(w)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.dropped:
+			return w.err
+		case event := <-w.events:
+			if !tenant.All(ctx) && event.Tenant != tenant.FromContext(ctx) {
+				continue
+			}
+			if eventType != "" && event.Type != eventType {
+				continue
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+}
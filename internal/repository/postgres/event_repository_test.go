@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/repository/repotest"
+	"github.com/godev/events-service/internal/repository/sqlschema"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// testPool connects to testURL and skips the test when there is no server.
+// Every test gets a schema of its own that is dropped when it ends.
+func testPool(t *testing.T) *pgxpool.Pool {
+	url := testURL
+	if url == "" {
+		t.Skipf("POSTGRES_TEST_URL is not set and the embedded server did not start: %v", embeddedErr)
+	}
+	ctx := context.Background()
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	schema := "events_test_" + hex.EncodeToString(suffix)
+
+	admin, err := pgxpool.New(ctx, url)
+	require.NoError(t, err)
+	t.Cleanup(admin.Close)
+	_, err = admin.Exec(ctx, `CREATE SCHEMA `+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = admin.Exec(ctx, `DROP SCHEMA `+schema+` CASCADE`) })
+
+	config, err := pgxpool.ParseConfig(url)
+	require.NoError(t, err)
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// testRepository stores events in the database of pool.
+func testRepository(t *testing.T, pool *pgxpool.Pool) *EventRepository {
+	repo, err := NewEventRepository(pool)
+	require.NoError(t, err)
+	return repo.(*EventRepository)
+}
+
+func TestEventRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IEventRepository {
+		return testRepository(t, testPool(t))
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+	require.NoError(t, Migrate(ctx, pool))
+	require.NoError(t, Migrate(ctx, pool), "migrating twice must not fail")
+
+	all, err := sqlschema.Load(migrations, "migrations")
+	require.NoError(t, err)
+
+	var applied int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(all), applied)
+}
+
+func TestWatch(t *testing.T) {
+	repo := testRepository(t, testPool(t))
+	ctx, cancel := context.WithTimeout(tenant.WithTenant(context.Background(), "team-a"), 10*time.Second)
+	defer cancel()
+
+	received := make(chan model.Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.Watch(ctx, "deploy", func(event model.Event) error {
+			received <- event
+			return nil
+		})
+	}()
+
+	// Changes are only delivered once the watcher is listening.
+	pool := repo.pool
+	require.Eventually(t, func() bool {
+		var listeners int
+		err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM pg_stat_activity WHERE query = $1`,
+			"LISTEN "+changesChannel).Scan(&listeners)
+		require.NoError(t, err)
+		return listeners > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, repo.Create(ctx, &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: time.Now()}))
+
+	// A rolled back change is never seen.
+	_ = repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		require.NoError(t, repo.Create(txCtx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}))
+		return context.Canceled
+	})
+
+	event := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, event))
+	finishedAt := time.Now()
+	event.State = model.EventStateFinished
+	event.FinishedAt = &finishedAt
+	require.NoError(t, repo.Update(ctx, event))
+
+	first := <-received
+	assert.Equal(t, event.ID, first.ID)
+	assert.Equal(t, model.EventStateStarted, first.State)
+	second := <-received
+	assert.Equal(t, model.EventStateFinished, second.State)
+	assert.Equal(t, int64(2), second.Version)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, received)
+}
+
+func TestNestedTransactionRecovers(t *testing.T) {
+	repo := testRepository(t, testPool(t))
+	ctx := tenant.WithTenant(context.Background(), "team-a")
+	require.NoError(t, repo.Create(ctx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}))
+
+	build := &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: time.Now()}
+	err := repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		err := repo.Create(txCtx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()})
+		require.ErrorIs(t, err, repository.ErrUnfinishedExists)
+		// Only the savepoint of the failed Create is rolled back, so the
+		// transaction goes on.
+		return repo.Create(txCtx, build)
+	})
+	require.NoError(t, err)
+
+	found, err := repo.FindUnfinishedByType(ctx, "build", operation.Finish)
+	require.NoError(t, err)
+	assert.Equal(t, build.ID, found.ID)
+}
+
+func TestWatchSharesOneConnection(t *testing.T) {
+	repo := testRepository(t, testPool(t))
+	ctx, cancel := context.WithTimeout(tenant.WithTenant(context.Background(), "team-a"), 10*time.Second)
+	defer cancel()
+
+	const watchers = 3
+	received := make(chan model.Event, watchers)
+	done := make(chan error, watchers)
+	for i := 0; i < watchers; i++ {
+		go func() {
+			done <- repo.Watch(ctx, "", func(event model.Event) error {
+				received <- event
+				return nil
+			})
+		}()
+	}
+
+	listening := func() (n int) {
+		err := repo.pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM pg_stat_activity WHERE query = $1`,
+			"LISTEN "+changesChannel).Scan(&n)
+		require.NoError(t, err)
+		return n
+	}
+	require.Eventually(t, func() bool {
+		repo.listener.mu.Lock()
+		defer repo.listener.mu.Unlock()
+		return len(repo.listener.subs) == watchers
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Eventually(t, func() bool { return listening() == 1 }, 5*time.Second, 50*time.Millisecond)
+
+	event := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, event))
+	for i := 0; i < watchers; i++ {
+		assert.Equal(t, event.ID, (<-received).ID)
+	}
+
+	// The connection is closed once the last watcher leaves.
+	cancel()
+	for i := 0; i < watchers; i++ {
+		assert.ErrorIs(t, <-done, context.Canceled)
+	}
+	assert.Eventually(t, func() bool { return listening() == 0 }, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+func insertHistory(ctx context.Context, pool *pgxpool.Pool, entry *model.HistoryEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+
+	event, err := json.Marshal(entry.Event)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, pool).Exec(ctx, `
+		INSERT INTO event_history (id, event_id, tenant, transition, event, current_version, actor, request_id, at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		entry.ID.Hex(), entry.EventID.Hex(), entry.Tenant, string(entry.Transition), event,
+		entry.CurrentVersion, entry.Actor, entry.RequestID, entry.At)
+	return err
+}
+
+func (r *EventRepository) History(ctx context.Context, id primitive.ObjectID) (_ []model.HistoryEntry, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.History", attribute.String("event.id", id.Hex()))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	cond.add("event_id = ?", id.Hex())
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT id, tenant, transition, event, current_version, actor, request_id, at
+		FROM event_history`+cond.where()+`
+		ORDER BY seq`, cond.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.HistoryEntry
+	for rows.Next() {
+		var (
+			entry      model.HistoryEntry
+			entryID    string
+			transition string
+			event      []byte
+		)
+		err := rows.Scan(&entryID, &entry.Tenant, &transition, &event, &entry.CurrentVersion,
+			&entry.Actor, &entry.RequestID, &entry.At)
+		if err != nil {
+			return nil, err
+		}
+		if entry.ID, err = primitive.ObjectIDFromHex(entryID); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(event, &entry.Event); err != nil {
+			return nil, err
+		}
+		entry.EventID = id
+		entry.Transition = model.Transition(transition)
+		entry.At = entry.At.UTC()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// testURL is the server the tests run against: POSTGRES_TEST_URL or, when
+// it is not set, an embedded server started by TestMain.
+var testURL string
+
+// embeddedErr tells why there is no embedded server.
+var embeddedErr error
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	flag.Parse()
+
+	testURL = os.Getenv("POSTGRES_TEST_URL")
+	if testURL == "" {
+		if testing.Short() {
+			embeddedErr = fmt.Errorf("not started with -short")
+		} else {
+			stop, url, err := startEmbedded()
+			if err != nil {
+				embeddedErr = err
+			} else {
+				defer stop()
+				testURL = url
+			}
+		}
+	}
+
+	return m.Run()
+}
+
+// startEmbedded runs the PostgreSQL version of docker-compose.yml on a free
+// port. The binaries are downloaded on the first run and cached in
+// ~/.embedded-postgres-go.
+func startEmbedded() (stop func(), url string, err error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, "", err
+	}
+	runtime, err := os.MkdirTemp("", "events-postgres-")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var log bytes.Buffer
+	config := embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V16).
+		Port(port).
+		Database("events").
+		Username("events").
+		Password("events").
+		RuntimePath(runtime).
+		Logger(&log)
+	server := embeddedpostgres.NewDatabase(config)
+	if err := server.Start(); err != nil {
+		_ = os.RemoveAll(runtime)
+		return nil, "", fmt.Errorf("%w\n%s", err, log.Bytes())
+	}
+
+	stop = func() {
+		_ = server.Stop()
+		_ = os.RemoveAll(runtime)
+	}
+	return stop, config.GetConnectionURL() + "?sslmode=disable", nil
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
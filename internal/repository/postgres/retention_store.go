@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/retention"
+)
+
+type RetentionStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewRetentionStore finds expired events in the events table. Deleting them
+// also deletes their history; the audit log is kept. The schema is created
+// by the migrations NewEventRepository runs.
+func NewRetentionStore(pool *pgxpool.Pool) retention.Store {
+	return &RetentionStore{pool: pool}
+}
+
+func expiredConditions(rule retention.Rule) *conditions {
+	cond := &conditions{}
+	cond.add("state = ?", int(model.EventStateFinished))
+	cond.add("finished_at < ?", rule.Before)
+	if rule.Type != "" {
+		cond.add("type = ?", rule.Type)
+	} else if len(rule.Exclude) > 0 {
+		cond.add("type <> ALL(?)", rule.Exclude)
+	}
+	return cond
+}
+
+func (s *RetentionStore) Expired(ctx context.Context, rule retention.Rule, limit int64) ([]model.Event, error) {
+	cond := expiredConditions(rule)
+	where := cond.where()
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+eventColumns+` FROM events`+where+` ORDER BY finished_at LIMIT `+cond.arg(limitOf(limit)),
+		cond.args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (s *RetentionStore) CountExpired(ctx context.Context, rule retention.Rule) (int64, error) {
+	cond := expiredConditions(rule)
+	var count int64
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM events`+cond.where(), cond.args...).Scan(&count)
+	return count, err
+}
+
+func (s *RetentionStore) Delete(ctx context.Context, ids []primitive.ObjectID) error {
+	hexIDs := make([]string, len(ids))
+	for i, id := range ids {
+		hexIDs[i] = id.Hex()
+	}
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM events WHERE id = ANY($1)`, hexIDs); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM event_history WHERE event_id = ANY($1)`, hexIDs)
+		return err
+	})
+}
+
+// retentionLockKey identifies the advisory lock of retention among those
+// of other applications sharing the database.
+const retentionLockKey int64 = 0x6576656e7473 // "events"
+
+type retentionLock struct {
+	pool *pgxpool.Pool
+}
+
+// NewRetentionLock lets one instance at a time purge expired events. It is
+// a session advisory lock, so the server releases it when the connection of
+// an instance that died is closed.
+func NewRetentionLock(pool *pgxpool.Pool) retention.Lock {
+	return &retentionLock{pool: pool}
+}
+
+func (l *retentionLock) Acquire(ctx context.Context) (context.Context, func() error, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, retentionLockKey).Scan(&locked); err != nil {
+		conn.Release()
+		return nil, nil, err
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil, retention.ErrLocked
+	}
+
+	release := func() error {
+		defer conn.Release()
+		_, err := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, retentionLockKey)
+		return err
+	}
+	return ctx, release, nil
+}
+
+type tableArchiver struct {
+	pool *pgxpool.Pool
+}
+
+// NewTableArchiver copies events to the events_archive table.
+func NewTableArchiver(pool *pgxpool.Pool) retention.Archiver {
+	return &tableArchiver{pool: pool}
+}
+
+func (a *tableArchiver) Archive(ctx context.Context, events []model.Event) error {
+	batch := &pgx.Batch{}
+	for _, event := range events {
+		doc, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		// Events archived by an earlier, interrupted run are already there.
+		batch.Queue(`INSERT INTO events_archive (id, tenant, type, event) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id) DO NOTHING`, event.ID.Hex(), event.Tenant, event.Type, doc)
+	}
+	return a.pool.SendBatch(ctx, batch).Close()
+}
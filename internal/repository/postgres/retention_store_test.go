@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/repository/repotest"
+	"github.com/godev/events-service/internal/retention"
+)
+
+func TestRetentionStore(t *testing.T) {
+	repotest.RunRetention(t, func(t *testing.T) repotest.Retention {
+		pool := testPool(t)
+		return repotest.Retention{
+			Events:   testRepository(t, pool),
+			Store:    NewRetentionStore(pool),
+			Archiver: NewTableArchiver(pool),
+			Archived: func(ctx context.Context, id primitive.ObjectID) (n int64, err error) {
+				err = pool.QueryRow(ctx, `SELECT COUNT(*) FROM events_archive WHERE id = $1`, id.Hex()).Scan(&n)
+				return n, err
+			},
+		}
+	})
+}
+
+func TestRetentionLock(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	_, release, err := NewRetentionLock(pool).Acquire(ctx)
+	require.NoError(t, err)
+	_, _, err = NewRetentionLock(pool).Acquire(ctx)
+	assert.ErrorIs(t, err, retention.ErrLocked)
+
+	require.NoError(t, release())
+	_, release, err = NewRetentionLock(pool).Acquire(ctx)
+	require.NoError(t, err)
+	require.NoError(t, release())
+}
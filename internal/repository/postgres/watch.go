@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+// watchBuffer is how many changes a watcher may fall behind before it is
+// dropped.
+const watchBuffer = 256
+
+var errWatchLagged = errors.New("watcher fell too far behind the changes")
+
+type watcher struct {
+	events chan model.Event
+	// dropped is closed, after err is set, when the watcher no longer gets
+	// the changes.
+	dropped chan struct{}
+	err     error
+}
+
+// listener holds one connection that listens for the notifications of
+// changesChannel while there are watchers, and fans them out to every
+// Watch call. The connection is opened by the first watcher and closed
+// when the last one leaves.
+type listener struct {
+	pool *pgxpool.Pool
+
+	mu   sync.Mutex
+	subs map[*watcher]struct{}
+	// stop ends the running listen, nil while there is none.
+	stop context.CancelFunc
+}
+
+func newListener(pool *pgxpool.Pool) *listener {
+	return &listener{pool: pool, subs: make(map[*watcher]struct{})}
+}
+
+func (l *listener) subscribe() *watcher {
+	w := &watcher{
+		events:  make(chan model.Event, watchBuffer),
+		dropped: make(chan struct{}),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.subs[w] = struct{}{}
+	if l.stop == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		l.stop = cancel
+		go l.listen(ctx)
+	}
+	return w
+}
+
+func (l *listener) unsubscribe(w *watcher) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.subs, w)
+	if len(l.subs) == 0 && l.stop != nil {
+		l.stop()
+		l.stop = nil
+	}
+}
+
+// listen receives notifications until ctx is cancelled. When the
+// connection fails, every watcher is dropped with the error; the next
+// Watch call listens again.
+func (l *listener) listen(ctx context.Context) {
+	err := l.receive(ctx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A cancelled listen was stopped by unsubscribe, which may already
+	// have let a new one start.
+	if ctx.Err() != nil {
+		return
+	}
+	for w := range l.subs {
+		l.drop(w, err)
+	}
+	l.stop()
+	l.stop = nil
+}
+
+func (l *listener) receive(ctx context.Context) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection is left listening and is interrupted mid-read when
+	// ctx is cancelled, so it is closed instead of going back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, `LISTEN `+changesChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event model.Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			return err
+		}
+		l.publish(event)
+	}
+}
+
+// publish hands event to every watcher without waiting for slow ones: a
+// watcher whose buffer is full is dropped rather than silently skipping
+// the change.
+func (l *listener) publish(event model.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for w := range l.subs {
+		select {
+		case w.events <- event:
+		default:
+			l.drop(w, errWatchLagged)
+		}
+	}
+}
+
+// drop must be called with mu held.
+func (l *listener) drop(w *watcher, err error) {
+	w.err = err
+	close(w.dropped)
+	delete(l.subs, w)
+}
@@ -0,0 +1,86 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/internal/auth"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/reqctx"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// AuditFactory returns empty event and audit repositories sharing storage
+// that is private to the test.
+type AuditFactory func(t *testing.T) (repository.IEventRepository, repository.IAuditRepository)
+
+// RunAudit checks that changes of events are audited as they commit and
+// that the audit log is listed and filtered per tenant.
+func RunAudit(t *testing.T, newRepos AuditFactory) {
+	events, audit := newRepos(t)
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "ci", Method: auth.MethodAPIKey})
+	ctx = reqctx.WithRequestID(ctx, "req-1")
+	ctx = reqctx.WithClientIP(ctx, "10.0.0.1")
+	ctx = tenant.WithTenant(ctx, "team-a")
+
+	event := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}
+	require.NoError(t, events.Create(ctx, event))
+	finishedAt := time.Now()
+	event.State = model.EventStateFinished
+	event.FinishedAt = &finishedAt
+	require.NoError(t, events.Update(ctx, event))
+
+	// A rolled back change leaves no audit entry behind.
+	errRollback := errors.New("rollback")
+	err := events.WithTransaction(ctx, func(txCtx context.Context) error {
+		require.NoError(t, events.Create(txCtx, &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: time.Now()}))
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	entries, err := audit.List(ctx, model.AuditFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	finish, start := entries[0], entries[1]
+	assert.Equal(t, model.AuditActionStart, start.Action)
+	assert.Nil(t, start.Before)
+	assert.Equal(t, model.EventStateStarted, start.After)
+	assert.Equal(t, model.AuditActionFinish, finish.Action)
+	require.NotNil(t, finish.Before)
+	assert.Equal(t, model.EventStateStarted, *finish.Before)
+	assert.Equal(t, model.EventStateFinished, finish.After)
+	for _, entry := range entries {
+		assert.Equal(t, "team-a", entry.Tenant)
+		assert.Equal(t, "ci", entry.Actor)
+		assert.Equal(t, event.ID, entry.EventID)
+		assert.Equal(t, "deploy", entry.EventType)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.Equal(t, "10.0.0.1", entry.SourceIP)
+	}
+
+	filtered, err := audit.List(ctx, model.AuditFilter{Action: model.AuditActionStart, EventID: event.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, start.ID, filtered[0].ID)
+
+	page, err := audit.List(ctx, model.AuditFilter{Offset: 1, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, start.ID, page[0].ID)
+
+	none, err := audit.List(ctx, model.AuditFilter{From: time.Now().Add(time.Minute), Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	other, err := audit.List(tenant.WithTenant(context.Background(), "team-b"), model.AuditFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, other, "tenants must not see each other's audit log")
+}
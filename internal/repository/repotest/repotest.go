@@ -0,0 +1,322 @@
+// Package repotest is the behaviour every repository.IEventRepository
+// implementation must provide, written as a reusable test suite.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// Factory returns an empty repository that is private to the test.
+type Factory func(t *testing.T) repository.IEventRepository
+
+// Run runs the suite against repositories created by newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.IEventRepository)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"SingleUnfinishedPerType", testSingleUnfinishedPerType},
+		{"UpdateChecksVersion", testUpdateChecksVersion},
+		{"ListOrderAndPaging", testListOrderAndPaging},
+		{"Stats", testStats},
+		{"TransactionRollback", testTransactionRollback},
+		{"TenantIsolation", testTenantIsolation},
+		{"History", testHistory},
+		{"ConflictOutlivesTransaction", testConflictOutlivesTransaction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func started(eventType string, startedAt time.Time) *model.Event {
+	return &model.Event{
+		Type:      eventType,
+		State:     model.EventStateStarted,
+		StartedAt: startedAt.UTC().Truncate(time.Millisecond),
+	}
+}
+
+func finish(t *testing.T, ctx context.Context, repo repository.IEventRepository, event *model.Event, at time.Time) {
+	t.Helper()
+	finishedAt := at.UTC().Truncate(time.Millisecond)
+	event.State = model.EventStateFinished
+	event.FinishedAt = &finishedAt
+	require.NoError(t, repo.Update(ctx, event))
+}
+
+func testCreateAndFind(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	event := started("deploy", time.Now())
+	require.NoError(t, repo.Create(ctx, event))
+	assert.False(t, event.ID.IsZero())
+	assert.Equal(t, int64(1), event.Version)
+	assert.Equal(t, tenant.Default, event.Tenant)
+
+	found, err := repo.FindByID(ctx, event.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, event.Type, found.Type)
+	assert.Equal(t, model.EventStateStarted, found.State)
+	assert.True(t, event.StartedAt.Equal(found.StartedAt))
+
+	unfinished, err := repo.FindUnfinishedByType(ctx, "deploy", operation.Finish)
+	require.NoError(t, err)
+	require.NotNil(t, unfinished)
+	assert.Equal(t, event.ID, unfinished.ID)
+
+	missing, err := repo.FindByID(ctx, primitive.NewObjectID())
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	none, err := repo.FindUnfinishedByType(ctx, "build", operation.Finish)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
+
+func testSingleUnfinishedPerType(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	first := started("deploy", time.Now())
+	require.NoError(t, repo.Create(ctx, first))
+
+	err := repo.Create(ctx, started("deploy", time.Now()))
+	assert.ErrorIs(t, err, repository.ErrUnfinishedExists)
+
+	finish(t, ctx, repo, first, time.Now())
+	assert.NoError(t, repo.Create(ctx, started("deploy", time.Now())))
+}
+
+func testUpdateChecksVersion(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	event := started("deploy", time.Now())
+	require.NoError(t, repo.Create(ctx, event))
+
+	stale := *event
+	finish(t, ctx, repo, event, time.Now())
+
+	found, err := repo.FindByID(ctx, event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.EventStateFinished, found.State)
+	assert.Equal(t, int64(2), found.Version)
+	require.NotNil(t, found.FinishedAt)
+
+	now := time.Now()
+	stale.State = model.EventStateFinished
+	stale.FinishedAt = &now
+	assert.ErrorIs(t, repo.Update(ctx, &stale), repository.ErrVersionConflict)
+}
+
+func testListOrderAndPaging(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+	for i, eventType := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, repo.Create(ctx, started(eventType, base.Add(time.Duration(i)*time.Minute))))
+	}
+
+	events, err := repo.List(ctx, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, []string{"d", "c", "b", "a"}, types(events))
+
+	page, err := repo.List(ctx, "", 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, types(page))
+
+	filtered, err := repo.List(ctx, "b", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, types(filtered))
+}
+
+func testStats(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	first := started("deploy", base)
+	require.NoError(t, repo.Create(ctx, first))
+	finish(t, ctx, repo, first, base.Add(2*time.Second))
+	second := started("deploy", base.Add(time.Minute))
+	require.NoError(t, repo.Create(ctx, second))
+	finish(t, ctx, repo, second, base.Add(time.Minute+4*time.Second))
+	require.NoError(t, repo.Create(ctx, started("deploy", base.Add(2*time.Minute))))
+	require.NoError(t, repo.Create(ctx, started("build", base)))
+
+	stats, err := repo.Stats(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	assert.Equal(t, "build", stats[0].Type)
+	assert.Equal(t, int64(1), stats[0].Running)
+	assert.Equal(t, int64(0), stats[0].Finished)
+
+	assert.Equal(t, "deploy", stats[1].Type)
+	assert.Equal(t, tenant.Default, stats[1].Tenant)
+	assert.Equal(t, int64(1), stats[1].Running)
+	assert.Equal(t, int64(2), stats[1].Finished)
+	assert.InDelta(t, 3000, stats[1].AvgDurationMs, 1)
+	assert.True(t, base.Add(2*time.Minute).Truncate(time.Millisecond).Equal(stats[1].LastStartedAt))
+
+	filtered, err := repo.Stats(ctx, "build")
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "build", filtered[0].Type)
+}
+
+func testTransactionRollback(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	err := repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		require.NoError(t, repo.Create(txCtx, started("deploy", time.Now())))
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	event, err := repo.FindUnfinishedByType(ctx, "deploy", operation.Finish)
+	require.NoError(t, err)
+	assert.Nil(t, event)
+}
+
+func testTenantIsolation(t *testing.T, repo repository.IEventRepository) {
+	ctxA := tenant.WithTenant(context.Background(), "team-a")
+	ctxB := tenant.WithTenant(context.Background(), "team-b")
+
+	eventA := started("deploy", time.Now())
+	require.NoError(t, repo.Create(ctxA, eventA))
+	assert.Equal(t, "team-a", eventA.Tenant)
+
+	// The same type can run in both tenants at once.
+	eventB := started("deploy", time.Now())
+	require.NoError(t, repo.Create(ctxB, eventB))
+
+	unfinished, err := repo.FindUnfinishedByType(ctxB, "deploy", operation.Finish)
+	require.NoError(t, err)
+	require.NotNil(t, unfinished)
+	assert.Equal(t, eventB.ID, unfinished.ID)
+
+	found, err := repo.FindByID(ctxB, eventA.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found, "tenant B must not read tenant A's event")
+
+	// Finishing A's event from B must not touch it.
+	foreign := *eventA
+	now := time.Now()
+	foreign.State = model.EventStateFinished
+	foreign.FinishedAt = &now
+	assert.ErrorIs(t, repo.Update(ctxB, &foreign), repository.ErrVersionConflict)
+
+	found, err = repo.FindByID(ctxA, eventA.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, model.EventStateStarted, found.State)
+
+	listB, err := repo.List(ctxB, "", 0, 10)
+	require.NoError(t, err)
+	require.Len(t, listB, 1)
+	assert.Equal(t, eventB.ID, listB[0].ID)
+
+	listDefault, err := repo.List(context.Background(), "", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, listDefault)
+
+	statsA, err := repo.Stats(ctxA, "")
+	require.NoError(t, err)
+	require.Len(t, statsA, 1)
+	assert.Equal(t, "team-a", statsA[0].Tenant)
+	assert.Equal(t, int64(1), statsA[0].Running)
+
+	all, err := repo.Stats(tenant.WithAllTenants(context.Background()), "")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, []string{"team-a", "team-b"}, []string{all[0].Tenant, all[1].Tenant})
+}
+
+func testHistory(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	event := started("deploy", time.Now())
+	require.NoError(t, repo.Create(ctx, event))
+
+	stale := *event
+	finish(t, ctx, repo, event, time.Now())
+
+	now := time.Now()
+	stale.State = model.EventStateFinished
+	stale.FinishedAt = &now
+	require.ErrorIs(t, repo.Update(ctx, &stale), repository.ErrVersionConflict)
+
+	history, err := repo.History(ctx, event.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	assert.Equal(t, model.TransitionStarted, history[0].Transition)
+	assert.Equal(t, model.EventStateStarted, history[0].Event.State)
+	assert.Equal(t, int64(1), history[0].Event.Version)
+
+	assert.Equal(t, model.TransitionFinished, history[1].Transition)
+	assert.Equal(t, model.EventStateFinished, history[1].Event.State)
+	assert.Equal(t, int64(2), history[1].Event.Version)
+	require.NotNil(t, history[1].Event.FinishedAt)
+
+	assert.Equal(t, model.TransitionConflict, history[2].Transition)
+	assert.Equal(t, int64(1), history[2].Event.Version)
+	assert.Equal(t, int64(2), history[2].CurrentVersion)
+
+	for _, entry := range history {
+		assert.Equal(t, event.ID, entry.EventID)
+		assert.Equal(t, tenant.Default, entry.Tenant)
+	}
+
+	other, err := repo.History(tenant.WithTenant(ctx, "team-b"), event.ID)
+	require.NoError(t, err)
+	assert.Empty(t, other)
+}
+
+func testConflictOutlivesTransaction(t *testing.T, repo repository.IEventRepository) {
+	ctx := context.Background()
+	event := started("deploy", time.Now())
+	require.NoError(t, repo.Create(ctx, event))
+	stale := *event
+	finish(t, ctx, repo, event, time.Now())
+
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, repo.Create(ctx, started("build", time.Now())))
+		now := time.Now()
+		stale.State = model.EventStateFinished
+		stale.FinishedAt = &now
+		return repo.Update(ctx, &stale)
+	})
+	require.ErrorIs(t, err, repository.ErrVersionConflict)
+
+	builds, err := repo.List(ctx, "build", 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, builds, "the transaction is rolled back")
+
+	history, err := repo.History(ctx, event.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 3, "the conflict is recorded all the same")
+	assert.Equal(t, model.TransitionConflict, history[2].Transition)
+	assert.Equal(t, int64(2), history[2].CurrentVersion)
+}
+
+func types(events []model.Event) []string {
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Type)
+	}
+	return result
+}
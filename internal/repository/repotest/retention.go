@@ -0,0 +1,72 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/retention"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// Retention is the retention support of a backend, on storage that is
+// private to the test.
+type Retention struct {
+	Events   repository.IEventRepository
+	Store    retention.Store
+	Archiver retention.Archiver
+	// Archived counts the archived copies of the event with id.
+	Archived func(ctx context.Context, id primitive.ObjectID) (int64, error)
+}
+
+// RunRetention checks that expired events are found, archived once and
+// deleted together with their history.
+func RunRetention(t *testing.T, newRetention func(t *testing.T) Retention) {
+	r := newRetention(t)
+	ctx := tenant.WithAllTenants(context.Background())
+
+	create := func(eventType string, finishedAt time.Time) *model.Event {
+		event := &model.Event{Type: eventType, State: model.EventStateStarted, StartedAt: finishedAt.Add(-time.Minute)}
+		require.NoError(t, r.Events.Create(ctx, event))
+		event.State = model.EventStateFinished
+		event.FinishedAt = &finishedAt
+		require.NoError(t, r.Events.Update(ctx, event))
+		return event
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	old := create("deploy", now.Add(-48*time.Hour))
+	create("deploy", now)
+	create("build", now.Add(-48*time.Hour))
+
+	rule := retention.Rule{Exclude: []string{"build"}, Before: now.Add(-24 * time.Hour)}
+	count, err := r.Store.CountExpired(ctx, rule)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	expired, err := r.Store.Expired(ctx, rule, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, old.ID, expired[0].ID)
+
+	require.NoError(t, r.Archiver.Archive(ctx, expired))
+	require.NoError(t, r.Archiver.Archive(ctx, expired), "archiving twice must not fail")
+	require.NoError(t, r.Store.Delete(ctx, []primitive.ObjectID{old.ID}))
+
+	found, err := r.Events.FindByID(ctx, old.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	history, err := r.Events.History(ctx, old.ID)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	archived, err := r.Archived(ctx, old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), archived)
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository reads the audit log that EventRepository writes; the
+// table is created by the migrations NewEventRepository runs.
+func NewAuditRepository(db *sql.DB) repository.IAuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func insertAudit(ctx context.Context, db *sql.DB, entry *model.AuditEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+
+	var before sql.NullInt64
+	if entry.Before != nil {
+		before = sql.NullInt64{Int64: int64(*entry.Before), Valid: true}
+	}
+
+	_, err := conn(ctx, db).ExecContext(ctx, `
+		INSERT INTO audit (id, tenant, actor, action, event_id, event_type, state_before,
+			state_after, request_id, source_ip, at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID.Hex(), entry.Tenant, entry.Actor, entry.Action, entry.EventID.Hex(), entry.EventType,
+		before, entry.After, entry.RequestID, entry.SourceIP, millis(entry.At))
+	return err
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter model.AuditFilter) (_ []model.AuditEntry, err error) {
+	ctx, span := tracing.Start(ctx, "AuditRepository.List",
+		attribute.String("audit.actor", filter.Actor),
+		attribute.String("audit.action", string(filter.Action)))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	if filter.Actor != "" {
+		cond.add("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		cond.add("action = ?", filter.Action)
+	}
+	if !filter.EventID.IsZero() {
+		cond.add("event_id = ?", filter.EventID.Hex())
+	}
+	if filter.EventType != "" {
+		cond.add("event_type = ?", filter.EventType)
+	}
+	if !filter.From.IsZero() {
+		cond.add("at >= ?", millis(filter.From))
+	}
+	if !filter.To.IsZero() {
+		cond.add("at < ?", millis(filter.To))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, tenant, actor, action, event_id, event_type, state_before, state_after,
+			request_id, source_ip, at
+		FROM audit`+cond.where()+`
+		ORDER BY at DESC, seq DESC
+		LIMIT ? OFFSET ?`, append(cond.args, limitOf(filter.Limit), filter.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var (
+			entry   model.AuditEntry
+			entryID string
+			eventID string
+			before  sql.NullInt64
+			at      int64
+		)
+		err := rows.Scan(&entryID, &entry.Tenant, &entry.Actor, &entry.Action, &eventID, &entry.EventType,
+			&before, &entry.After, &entry.RequestID, &entry.SourceIP, &at)
+		if err != nil {
+			return nil, err
+		}
+		if entry.ID, err = primitive.ObjectIDFromHex(entryID); err != nil {
+			return nil, err
+		}
+		if entry.EventID, err = primitive.ObjectIDFromHex(eventID); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			state := model.EventState(before.Int64)
+			entry.Before = &state
+		}
+		entry.At = fromMillis(at)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,15 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/repository/repotest"
+)
+
+func TestAuditRepository(t *testing.T) {
+	repotest.RunAudit(t, func(t *testing.T) (repository.IEventRepository, repository.IAuditRepository) {
+		database := testDB(t)
+		return testRepository(t, database), NewAuditRepository(database)
+	})
+}
@@ -0,0 +1,434 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tenant"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+const eventColumns = "id, tenant, type, state, started_at, finished_at, version"
+
+type EventRepository struct {
+	db       *sql.DB
+	watchers *watchers
+}
+
+// NewEventRepository stores events in db, migrating its schema first.
+func NewEventRepository(db *sql.DB) (repository.IEventRepository, error) {
+	if err := Migrate(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+
+	return &EventRepository{
+		db:       db,
+		watchers: newWatchers(),
+	}, nil
+}
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+// txState is the transaction of a context and the events it changed, which
+// are passed to watchers once it commits.
+type txState struct {
+	tx      *sql.Tx
+	changed []model.Event
+	// conflicts are the history entries of rejected updates. They are
+	// written once the transaction ends, however it ends.
+	conflicts []*model.HistoryEntry
+}
+
+// conn returns the transaction of ctx, or db outside of one.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+	return db
+}
+
+// conditions builds the WHERE clause of a query.
+type conditions struct {
+	clauses []string
+	args    []any
+}
+
+// scoped starts conditions that restrict a query to the tenant of ctx.
+func scoped(ctx context.Context) *conditions {
+	c := &conditions{}
+	if !tenant.All(ctx) {
+		c.add("tenant = ?", tenant.FromContext(ctx))
+	}
+	return c
+}
+
+func (c *conditions) add(clause string, args ...any) {
+	c.clauses = append(c.clauses, clause)
+	c.args = append(c.args, args...)
+}
+
+func (c *conditions) where() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// millis converts t to the Unix milliseconds times are stored as.
+func millis(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
+}
+
+func nullMillis(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: millis(*t), Valid: true}
+}
+
+func fromNullMillis(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := fromMillis(ms.Int64)
+	return &t
+}
+
+// limitOf turns a limit of zero, which means no limit, into SQLite's -1.
+func limitOf(limit int64) int64 {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEvent(row scanner) (model.Event, error) {
+	var (
+		event      model.Event
+		id         string
+		startedAt  int64
+		finishedAt sql.NullInt64
+	)
+	err := row.Scan(&id, &event.Tenant, &event.Type, &event.State, &startedAt, &finishedAt, &event.Version)
+	if err != nil {
+		return event, err
+	}
+
+	if event.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return event, err
+	}
+	event.StartedAt = fromMillis(startedAt)
+	event.FinishedAt = fromNullMillis(finishedAt)
+	return event, nil
+}
+
+func scanEvents(rows *sql.Rows) ([]model.Event, error) {
+	defer rows.Close()
+
+	var events []model.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// isUniqueViolation reports whether err is a unique or primary key
+// constraint failure.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+func (r *EventRepository) Create(ctx context.Context, event *model.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Create", attribute.String("event.type", event.Type))
+	defer func() { tracing.End(span, err) }()
+
+	if !tenant.All(ctx) || event.Tenant == "" {
+		event.Tenant = tenant.FromContext(ctx)
+	}
+
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+
+	return r.WithTransaction(ctx, func(txCtx context.Context) error {
+		event.Version = 1
+		_, err := conn(txCtx, r.db).ExecContext(txCtx,
+			`INSERT INTO events (`+eventColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			event.ID.Hex(), event.Tenant, event.Type, event.State,
+			millis(event.StartedAt), nullMillis(event.FinishedAt), event.Version)
+		if isUniqueViolation(err) {
+			return repository.ErrUnfinishedExists
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := insertAudit(txCtx, r.db, repository.NewAuditEntry(txCtx, model.AuditActionStart, event, nil)); err != nil {
+			return err
+		}
+		if err := insertHistory(txCtx, r.db, repository.NewHistoryEntry(txCtx, model.TransitionStarted, *event)); err != nil {
+			return err
+		}
+
+		changed(txCtx, *event)
+		return nil
+	})
+}
+
+func (r *EventRepository) FindUnfinishedByType(ctx context.Context, eventType, _ string) (_ *model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.FindUnfinishedByType", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	cond.add("type = ?", eventType)
+	cond.add("state = ?", model.EventStateStarted)
+	return r.findOne(ctx, cond)
+}
+
+func (r *EventRepository) FindByID(ctx context.Context, id primitive.ObjectID) (_ *model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.FindByID", attribute.String("event.id", id.Hex()))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	cond.add("id = ?", id.Hex())
+	return r.findOne(ctx, cond)
+}
+
+// findOne returns the event that matches cond, or nil when there is none.
+func (r *EventRepository) findOne(ctx context.Context, cond *conditions) (*model.Event, error) {
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+eventColumns+` FROM events`+cond.where(), cond.args...)
+	event, err := scanEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *EventRepository) Update(ctx context.Context, event *model.Event) (err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Update",
+		attribute.String("event.id", event.ID.Hex()),
+		attribute.String("event.type", event.Type),
+		attribute.Int64("event.version", event.Version))
+	defer func() { tracing.End(span, err) }()
+
+	return r.WithTransaction(ctx, func(txCtx context.Context) error {
+		cond := scoped(txCtx)
+		cond.add("id = ?", event.ID.Hex())
+
+		// The previous state of the event goes into the audit entry.
+		before, err := r.findOne(txCtx, cond)
+		if err != nil {
+			return err
+		}
+		if before == nil || before.Version != event.Version {
+			conflict(txCtx, event, before)
+			return repository.ErrVersionConflict
+		}
+
+		cond.add("version = ?", event.Version)
+		args := append([]any{event.State, nullMillis(event.FinishedAt), event.Version + 1}, cond.args...)
+		res, err := conn(txCtx, r.db).ExecContext(txCtx,
+			`UPDATE events SET state = ?, finished_at = ?, version = ?`+cond.where(), args...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			conflict(txCtx, event, before)
+			return repository.ErrVersionConflict
+		}
+
+		entry := repository.NewAuditEntry(txCtx, repository.ChangeAction(before.State, event.State), event, &before.State)
+		entry.Tenant = before.Tenant
+		if err := insertAudit(txCtx, r.db, entry); err != nil {
+			return err
+		}
+
+		after := *before
+		after.State = event.State
+		after.FinishedAt = event.FinishedAt
+		after.Version = event.Version + 1
+		change := repository.ChangeTransition(before.State, after.State)
+		if err := insertHistory(txCtx, r.db, repository.NewHistoryEntry(txCtx, change, after)); err != nil {
+			return err
+		}
+
+		changed(txCtx, after)
+		return nil
+	})
+}
+
+func (r *EventRepository) List(ctx context.Context, eventType string, offset, limit int64) (_ []model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.List", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	if eventType != "" {
+		cond.add("type = ?", eventType)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx,
+		`SELECT `+eventColumns+` FROM events`+cond.where()+` ORDER BY started_at DESC LIMIT ? OFFSET ?`,
+		append(cond.args, limitOf(limit), offset)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (r *EventRepository) Stats(ctx context.Context, eventType string) (_ []model.EventStats, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.Stats", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	if eventType != "" {
+		cond.add("type = ?", eventType)
+	}
+
+	args := append([]any{model.EventStateStarted, model.EventStateFinished, model.EventStateFinished}, cond.args...)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT tenant, type,
+			SUM(state = ?),
+			SUM(state = ?),
+			COALESCE(AVG(CASE WHEN state = ? THEN finished_at - started_at END), 0),
+			MAX(started_at)
+		FROM events`+cond.where()+`
+		GROUP BY tenant, type
+		ORDER BY tenant, type`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []model.EventStats
+	for rows.Next() {
+		var (
+			s             model.EventStats
+			lastStartedAt int64
+		)
+		err := rows.Scan(&s.Tenant, &s.Type, &s.Running, &s.Finished, &s.AvgDurationMs, &lastStartedAt)
+		if err != nil {
+			return nil, err
+		}
+		s.LastStartedAt = fromMillis(lastStartedAt)
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// WithTransaction runs fn inside a SQLite transaction. When ctx already
+// carries a transaction (e.g. a batch of operations), fn joins it instead
+// of starting a nested one.
+func (r *EventRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	ctx, span := tracing.Start(ctx, "EventRepository.WithTransaction")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	state := &txState{tx: tx}
+
+	if err = fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		_ = tx.Rollback()
+	} else if err = tx.Commit(); err == nil {
+		for _, event := range state.changed {
+			r.watchers.publish(event)
+		}
+	}
+
+	// SQLite has a single connection, so rejected updates can only be
+	// recorded once the transaction has released it.
+	for _, entry := range state.conflicts {
+		if herr := insertHistory(ctx, r.db, entry); herr != nil {
+			err = errors.Join(err, herr)
+		}
+	}
+	return err
+}
+
+// changed queues event for the watchers until the transaction of ctx
+// commits.
+func changed(ctx context.Context, event model.Event) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.changed = append(state.changed, event)
+	}
+}
+
+// conflict queues the rejected update of event for the history, provided
+// the event exists at another version.
+func conflict(ctx context.Context, event *model.Event, current *model.Event) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok && current != nil {
+		state.conflicts = append(state.conflicts, repository.NewConflictEntry(ctx, *event, current))
+	}
+}
+
+// Watch calls fn with every event that this repository creates or updates
+// until ctx is done or fn fails. SQLite has no change stream, so changes
+// made by other processes sharing the database file are not seen.
+func (r *EventRepository) Watch(ctx context.Context, eventType string, fn func(event model.Event) error) error {
+	w := r.watchers.subscribe()
+	defer r.watchers.unsubscribe(w)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.lagged:
+			return errWatchLagged
+		case event := <-w.events:
+			if !tenant.All(ctx) && event.Tenant != tenant.FromContext(ctx) {
+				continue
+			}
+			if eventType != "" && event.Type != eventType {
+				continue
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+}
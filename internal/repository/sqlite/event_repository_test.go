@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/config"
+	"github.com/godev/events-service/internal/db"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/repository/repotest"
+	"github.com/godev/events-service/internal/repository/sqlschema"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// testDB opens a database file that is removed when t ends.
+func testDB(t *testing.T) *sql.DB {
+	sqlite, err := db.NewSQLite(zap.NewNop(), &config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "events.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlite.Close() })
+	return sqlite.GetDB()
+}
+
+// testRepository stores events in database.
+func testRepository(t *testing.T, database *sql.DB) repository.IEventRepository {
+	repo, err := NewEventRepository(database)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestEventRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IEventRepository {
+		return testRepository(t, testDB(t))
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	database := testDB(t)
+	require.NoError(t, Migrate(context.Background(), database))
+	require.NoError(t, Migrate(context.Background(), database), "migrating twice must not fail")
+
+	all, err := sqlschema.Load(migrations, "migrations")
+	require.NoError(t, err)
+
+	var applied int
+	require.NoError(t, database.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(all), applied)
+}
+
+func TestWatch(t *testing.T) {
+	repo := testRepository(t, testDB(t))
+	ctx, cancel := context.WithTimeout(tenant.WithTenant(context.Background(), "team-a"), 5*time.Second)
+	defer cancel()
+
+	received := make(chan model.Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.Watch(ctx, "deploy", func(event model.Event) error {
+			received <- event
+			return nil
+		})
+	}()
+
+	// Wait until the watcher is subscribed before changing anything.
+	require.Eventually(t, func() bool {
+		w := repo.(*EventRepository).watchers
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return len(w.subs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	other := tenant.WithTenant(context.Background(), "team-b")
+	require.NoError(t, repo.Create(other, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}))
+	require.NoError(t, repo.Create(ctx, &model.Event{Type: "build", State: model.EventStateStarted, StartedAt: time.Now()}))
+
+	// A rolled back change is never seen.
+	_ = repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		require.NoError(t, repo.Create(txCtx, &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}))
+		return context.Canceled
+	})
+
+	event := &model.Event{Type: "deploy", State: model.EventStateStarted, StartedAt: time.Now()}
+	require.NoError(t, repo.Create(ctx, event))
+	finishedAt := time.Now()
+	event.State = model.EventStateFinished
+	event.FinishedAt = &finishedAt
+	require.NoError(t, repo.Update(ctx, event))
+
+	first := <-received
+	assert.Equal(t, event.ID, first.ID)
+	assert.Equal(t, model.EventStateStarted, first.State)
+	second := <-received
+	assert.Equal(t, model.EventStateFinished, second.State)
+	assert.Equal(t, int64(2), second.Version)
+	assert.Empty(t, received)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+func insertHistory(ctx context.Context, db *sql.DB, entry *model.HistoryEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+
+	event := entry.Event
+	_, err := conn(ctx, db).ExecContext(ctx, `
+		INSERT INTO event_history (id, event_id, tenant, transition, type, state, started_at,
+			finished_at, version, current_version, actor, request_id, at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID.Hex(), entry.EventID.Hex(), entry.Tenant, entry.Transition, event.Type, event.State,
+		millis(event.StartedAt), nullMillis(event.FinishedAt), event.Version, entry.CurrentVersion,
+		entry.Actor, entry.RequestID, millis(entry.At))
+	return err
+}
+
+func (r *EventRepository) History(ctx context.Context, id primitive.ObjectID) (_ []model.HistoryEntry, err error) {
+	ctx, span := tracing.Start(ctx, "EventRepository.History", attribute.String("event.id", id.Hex()))
+	defer func() { tracing.End(span, err) }()
+
+	cond := scoped(ctx)
+	cond.add("event_id = ?", id.Hex())
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, tenant, transition, type, state, started_at, finished_at, version,
+			current_version, actor, request_id, at
+		FROM event_history`+cond.where()+`
+		ORDER BY seq`, cond.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.HistoryEntry
+	for rows.Next() {
+		var (
+			entry      model.HistoryEntry
+			entryID    string
+			startedAt  int64
+			finishedAt sql.NullInt64
+			at         int64
+		)
+		err := rows.Scan(&entryID, &entry.Tenant, &entry.Transition, &entry.Event.Type, &entry.Event.State,
+			&startedAt, &finishedAt, &entry.Event.Version, &entry.CurrentVersion,
+			&entry.Actor, &entry.RequestID, &at)
+		if err != nil {
+			return nil, err
+		}
+		if entry.ID, err = primitive.ObjectIDFromHex(entryID); err != nil {
+			return nil, err
+		}
+		entry.EventID = id
+		entry.Event.ID = id
+		entry.Event.Tenant = entry.Tenant
+		entry.Event.StartedAt = fromMillis(startedAt)
+		entry.Event.FinishedAt = fromNullMillis(finishedAt)
+		entry.At = fromMillis(at)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,64 @@
+CREATE TABLE events (
+    id          TEXT    PRIMARY KEY,
+    tenant      TEXT    NOT NULL,
+    type        TEXT    NOT NULL,
+    state       INTEGER NOT NULL,
+    -- Times are Unix milliseconds, the precision MongoDB stores.
+    started_at  INTEGER NOT NULL,
+    finished_at INTEGER,
+    version     INTEGER NOT NULL
+);
+
+-- At most one unfinished event per type within a tenant.
+CREATE UNIQUE INDEX events_tenant_type_unfinished ON events (tenant, type) WHERE state = 0;
+CREATE INDEX events_tenant_type_state ON events (tenant, type, state);
+CREATE INDEX events_tenant_started_at ON events (tenant, started_at DESC);
+CREATE INDEX events_state_type_finished_at ON events (state, type, finished_at);
+
+CREATE TABLE audit (
+    seq          INTEGER PRIMARY KEY AUTOINCREMENT,
+    id           TEXT    NOT NULL UNIQUE,
+    tenant       TEXT    NOT NULL,
+    actor        TEXT    NOT NULL,
+    action       TEXT    NOT NULL,
+    event_id     TEXT    NOT NULL,
+    event_type   TEXT    NOT NULL,
+    state_before INTEGER,
+    state_after  INTEGER NOT NULL,
+    request_id   TEXT    NOT NULL,
+    source_ip    TEXT    NOT NULL,
+    at           INTEGER NOT NULL
+);
+
+CREATE INDEX audit_tenant_at ON audit (tenant, at DESC);
+CREATE INDEX audit_tenant_event_id_at ON audit (tenant, event_id, at DESC);
+CREATE INDEX audit_tenant_actor_at ON audit (tenant, actor, at DESC);
+
+CREATE TABLE event_history (
+    seq             INTEGER PRIMARY KEY AUTOINCREMENT,
+    id              TEXT    NOT NULL UNIQUE,
+    event_id        TEXT    NOT NULL,
+    tenant          TEXT    NOT NULL,
+    transition      TEXT    NOT NULL,
+    type            TEXT    NOT NULL,
+    state           INTEGER NOT NULL,
+    started_at      INTEGER NOT NULL,
+    finished_at     INTEGER,
+    version         INTEGER NOT NULL,
+    current_version INTEGER NOT NULL DEFAULT 0,
+    actor           TEXT    NOT NULL,
+    request_id      TEXT    NOT NULL,
+    at              INTEGER NOT NULL
+);
+
+CREATE INDEX event_history_tenant_event_id ON event_history (tenant, event_id, seq);
+
+CREATE TABLE events_archive (
+    id          TEXT    PRIMARY KEY,
+    tenant      TEXT    NOT NULL,
+    type        TEXT    NOT NULL,
+    state       INTEGER NOT NULL,
+    started_at  INTEGER NOT NULL,
+    finished_at INTEGER,
+    version     INTEGER NOT NULL
+);
@@ -0,0 +1,113 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/retention"
+)
+
+type RetentionStore struct {
+	db *sql.DB
+}
+
+// NewRetentionStore finds expired events in the events table. Deleting them
+// also deletes their history; the audit log is kept. The schema is created
+// by the migrations NewEventRepository runs.
+func NewRetentionStore(db *sql.DB) retention.Store {
+	return &RetentionStore{db: db}
+}
+
+// placeholders returns "?, ?, ?" for n values.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func expiredConditions(rule retention.Rule) *conditions {
+	cond := &conditions{}
+	cond.add("state = ?", model.EventStateFinished)
+	cond.add("finished_at < ?", millis(rule.Before))
+	if rule.Type != "" {
+		cond.add("type = ?", rule.Type)
+	} else if len(rule.Exclude) > 0 {
+		args := make([]any, len(rule.Exclude))
+		for i, eventType := range rule.Exclude {
+			args[i] = eventType
+		}
+		cond.add("type NOT IN ("+placeholders(len(args))+")", args...)
+	}
+	return cond
+}
+
+func (s *RetentionStore) Expired(ctx context.Context, rule retention.Rule, limit int64) ([]model.Event, error) {
+	cond := expiredConditions(rule)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+eventColumns+` FROM events`+cond.where()+` ORDER BY finished_at LIMIT ?`,
+		append(cond.args, limitOf(limit))...)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (s *RetentionStore) CountExpired(ctx context.Context, rule retention.Rule) (int64, error) {
+	cond := expiredConditions(rule)
+	var count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events`+cond.where(), cond.args...).Scan(&count)
+	return count, err
+}
+
+func (s *RetentionStore) Delete(ctx context.Context, ids []primitive.ObjectID) error {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id.Hex()
+	}
+	in := "(" + placeholders(len(args)) + ")"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id IN `+in, args...); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM event_history WHERE event_id IN `+in, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type tableArchiver struct {
+	db *sql.DB
+}
+
+// NewTableArchiver copies events to the events_archive table.
+func NewTableArchiver(db *sql.DB) retention.Archiver {
+	return &tableArchiver{db: db}
+}
+
+func (a *tableArchiver) Archive(ctx context.Context, events []model.Event) error {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Events archived by an earlier, interrupted run are already there.
+	for _, event := range events {
+		_, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO events_archive (`+eventColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			event.ID.Hex(), event.Tenant, event.Type, event.State,
+			millis(event.StartedAt), nullMillis(event.FinishedAt), event.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,25 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/godev/events-service/internal/repository/repotest"
+)
+
+func TestRetentionStore(t *testing.T) {
+	repotest.RunRetention(t, func(t *testing.T) repotest.Retention {
+		database := testDB(t)
+		return repotest.Retention{
+			Events:   testRepository(t, database),
+			Store:    NewRetentionStore(database),
+			Archiver: NewTableArchiver(database),
+			Archived: func(ctx context.Context, id primitive.ObjectID) (n int64, err error) {
+				err = database.QueryRowContext(ctx, `SELECT COUNT(*) FROM events_archive WHERE id = ?`, id.Hex()).Scan(&n)
+				return n, err
+			},
+		}
+	})
+}
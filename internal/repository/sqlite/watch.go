@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+// watchBuffer is how many changes a watcher may fall behind before it is
+// dropped.
+const watchBuffer = 256
+
+var errWatchLagged = errors.New("watcher fell too far behind the changes")
+
+type watcher struct {
+	events chan model.Event
+	// lagged is closed when the watcher missed a change.
+	lagged chan struct{}
+}
+
+// watchers fans out committed changes to every Watch call.
+type watchers struct {
+	mu   sync.Mutex
+	subs map[*watcher]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{subs: make(map[*watcher]struct{})}
+}
+
+func (ws *watchers) subscribe() *watcher {
+	w := &watcher{
+		events: make(chan model.Event, watchBuffer),
+		lagged: make(chan struct{}),
+	}
+	ws.mu.Lock()
+	ws.subs[w] = struct{}{}
+	ws.mu.Unlock()
+	return w
+}
+
+func (ws *watchers) unsubscribe(w *watcher) {
+	ws.mu.Lock()
+	delete(ws.subs, w)
+	ws.mu.Unlock()
+}
+
+// publish hands event to every watcher without waiting for slow ones: a
+// watcher whose buffer is full is dropped rather than silently skipping
+// the change.
+func (ws *watchers) publish(event model.Event) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	for w := range ws.subs {
+		select {
+		case w.events <- event:
+		default:
+			close(w.lagged)
+			delete(ws.subs, w)
+		}
+	}
+}
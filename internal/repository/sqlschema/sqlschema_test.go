@@ -0,0 +1,39 @@
+package sqlschema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_index.sql": {Data: []byte("CREATE INDEX i ON t (a);")},
+		"migrations/0001_init.sql":  {Data: []byte("CREATE TABLE t (a INTEGER);")},
+		"migrations/README.md":      {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "0001_init.sql", migrations[0].Name)
+	assert.Equal(t, "CREATE TABLE t (a INTEGER);", migrations[0].SQL)
+	assert.Equal(t, 2, migrations[1].Version)
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no version": {"m/init.sql": {}},
+		"duplicate":  {"m/0001_a.sql": {}, "m/1_b.sql": {}},
+		"zero":       {"m/0000_a.sql": {}},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(fsys, "m")
+			assert.Error(t, err)
+		})
+	}
+}
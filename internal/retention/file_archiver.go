@@ -0,0 +1,64 @@
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+type fileArchiver struct {
+	dir string
+	now func() time.Time
+}
+
+// NewFileArchiver writes every batch of events to its own gzip-compressed
+// NDJSON file in dir.
+func NewFileArchiver(dir string) (Archiver, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &fileArchiver{dir: dir, now: time.Now}, nil
+}
+
+func (a *fileArchiver) Archive(_ context.Context, events []model.Event) (err error) {
+	// The first event makes the name unique among batches written at the
+	// same time.
+	name := fmt.Sprintf("events-%s-%s.ndjson.gz", a.now().UTC().Format("20060102T150405Z"), events[0].ID.Hex())
+	path := filepath.Join(a.dir, name)
+
+	// The file only gets its final name once it is complete.
+	tmp, err := os.CreateTemp(a.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(gz)
+	for _, event := range events {
+		if err = enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
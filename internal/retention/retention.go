@@ -0,0 +1,236 @@
+// Package retention purges finished events once they are older than the
+// configured retention, optionally archiving them first.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// Policy tells how long finished events are kept. Types has the retention
+// of individual types; all other types use Default, and a zero Default
+// keeps them forever.
+type Policy struct {
+	Default time.Duration
+	Types   map[string]time.Duration
+}
+
+// Rule selects the finished events of Type that finished before Before.
+// An empty Type selects every type except those in Exclude.
+type Rule struct {
+	Type    string
+	Exclude []string
+	Before  time.Time
+}
+
+// Rules turns p into the rules that apply at now, in a stable order.
+func (p Policy) Rules(now time.Time) []Rule {
+	types := make([]string, 0, len(p.Types))
+	for eventType := range p.Types {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+
+	rules := make([]Rule, 0, len(types)+1)
+	for _, eventType := range types {
+		rules = append(rules, Rule{Type: eventType, Before: now.Add(-p.Types[eventType])})
+	}
+	if p.Default > 0 {
+		rules = append(rules, Rule{Exclude: types, Before: now.Add(-p.Default)})
+	}
+	return rules
+}
+
+// Store finds and deletes expired events across all tenants.
+type Store interface {
+	Expired(ctx context.Context, rule Rule, limit int64) ([]model.Event, error)
+	CountExpired(ctx context.Context, rule Rule) (int64, error)
+	// Delete removes the events and everything derived from them, except
+	// the audit log.
+	Delete(ctx context.Context, ids []primitive.ObjectID) error
+}
+
+// ErrLocked is returned by Lock.Acquire while another instance purges.
+var ErrLocked = errors.New("another instance is purging expired events")
+
+// Lock keeps instances that share the storage from purging at the same
+// time.
+type Lock interface {
+	// Acquire takes the lock or fails with ErrLocked. The returned context
+	// is done once the lock is lost; release gives it up.
+	Acquire(ctx context.Context) (_ context.Context, release func() error, err error)
+}
+
+// Archiver keeps a copy of events before they are deleted. Archiving the
+// same event twice must not fail.
+type Archiver interface {
+	Archive(ctx context.Context, events []model.Event) error
+}
+
+type Job struct {
+	store     Store
+	archiver  Archiver
+	lock      Lock
+	policy    atomic.Pointer[Policy]
+	batchSize int64
+	log       *zap.Logger
+}
+
+// NewJob purges what policy allows in batches of batchSize. archiver may be
+// nil to delete events without archiving them, and lock nil when no other
+// instance shares the storage.
+func NewJob(store Store, archiver Archiver, lock Lock, policy Policy, batchSize int64, log *zap.Logger) *Job {
+	j := &Job{
+		store:     store,
+		archiver:  archiver,
+		lock:      lock,
+		batchSize: batchSize,
+		log:       log,
+	}
+	j.SetPolicy(policy)
+	return j
+}
+
+// SetPolicy replaces the policy; a purge that is running keeps the rules it
+// started with.
+func (j *Job) SetPolicy(policy Policy) {
+	j.policy.Store(&policy)
+}
+
+// Run purges expired events every interval until ctx is done. Purges do
+// nothing while the policy keeps every event forever, and are skipped while
+// another instance holds the lock.
+func (j *Job) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce purges expired events unless another instance is doing so.
+func (j *Job) runOnce(ctx context.Context) {
+	if j.lock != nil {
+		lockCtx, release, err := j.lock.Acquire(ctx)
+		if errors.Is(err, ErrLocked) {
+			j.log.Debug("Skipped purging expired events", zap.Error(err))
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				j.log.Error("Failed to lock purging expired events", zap.Error(err))
+			}
+			return
+		}
+		defer func() {
+			if err := release(); err != nil {
+				j.log.Warn("Failed to unlock purging expired events", zap.Error(err))
+			}
+		}()
+		ctx = lockCtx
+	}
+
+	purged, err := j.Purge(ctx, time.Now())
+	if err != nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	switch {
+	case err != nil && !errors.Is(err, context.Canceled):
+		j.log.Error("Failed to purge expired events", zap.Int64("purged", purged), zap.Error(err))
+	case purged > 0:
+		j.log.Info("Purged expired events", zap.Int64("purged", purged))
+	}
+}
+
+// Purge archives and deletes every event that is expired at now and
+// returns how many were deleted.
+func (j *Job) Purge(ctx context.Context, now time.Time) (int64, error) {
+	ctx = tenant.WithAllTenants(ctx)
+
+	var purged int64
+	for _, rule := range j.policy.Load().Rules(now) {
+		for {
+			events, err := j.store.Expired(ctx, rule, j.batchSize)
+			if err != nil {
+				return purged, err
+			}
+			if len(events) == 0 {
+				break
+			}
+
+			if j.archiver != nil {
+				if err := j.archiver.Archive(ctx, events); err != nil {
+					return purged, fmt.Errorf("archive events: %w", err)
+				}
+			}
+
+			ids := make([]primitive.ObjectID, len(events))
+			for i, event := range events {
+				ids[i] = event.ID
+			}
+			if err := j.store.Delete(ctx, ids); err != nil {
+				return purged, err
+			}
+			purged += int64(len(events))
+		}
+	}
+	return purged, nil
+}
+
+// Preview returns how many events each rule would purge at now.
+func (j *Job) Preview(ctx context.Context, now time.Time) ([]model.RetentionPreview, error) {
+	ctx = tenant.WithAllTenants(ctx)
+
+	rules := j.policy.Load().Rules(now)
+	previews := make([]model.RetentionPreview, 0, len(rules))
+	for _, rule := range rules {
+		count, err := j.store.CountExpired(ctx, rule)
+		if err != nil {
+			return nil, err
+		}
+
+		preview := model.RetentionPreview{Type: rule.Type, FinishedBefore: rule.Before, Count: count}
+		if preview.Type == "" {
+			preview.Type = model.RetentionAllTypes
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+// ParseDuration reads durations like time.ParseDuration and also accepts
+// whole days such as "90d".
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,247 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+type fakeStore struct {
+	events []model.Event
+}
+
+func (s *fakeStore) matches(rule Rule, event model.Event) bool {
+	if event.State != model.EventStateFinished || !event.FinishedAt.Before(rule.Before) {
+		return false
+	}
+	if rule.Type != "" {
+		return event.Type == rule.Type
+	}
+	for _, excluded := range rule.Exclude {
+		if event.Type == excluded {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *fakeStore) Expired(_ context.Context, rule Rule, limit int64) ([]model.Event, error) {
+	var result []model.Event
+	for _, event := range s.events {
+		if s.matches(rule, event) && int64(len(result)) < limit {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeStore) CountExpired(_ context.Context, rule Rule) (int64, error) {
+	var count int64
+	for _, event := range s.events {
+		if s.matches(rule, event) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *fakeStore) Delete(_ context.Context, ids []primitive.ObjectID) error {
+	deleted := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	kept := s.events[:0]
+	for _, event := range s.events {
+		if !deleted[event.ID] {
+			kept = append(kept, event)
+		}
+	}
+	s.events = kept
+	return nil
+}
+
+type recordingArchiver struct {
+	batches [][]model.Event
+}
+
+func (a *recordingArchiver) Archive(_ context.Context, events []model.Event) error {
+	a.batches = append(a.batches, append([]model.Event(nil), events...))
+	return nil
+}
+
+func finishedEvent(eventType string, finishedAt time.Time) model.Event {
+	return model.Event{ID: primitive.NewObjectID(), Type: eventType, State: model.EventStateFinished, FinishedAt: &finishedAt}
+}
+
+func TestJob(t *testing.T) {
+	now := time.Now()
+	store := &fakeStore{events: []model.Event{
+		finishedEvent("deploy", now.Add(-40*24*time.Hour)),
+		finishedEvent("deploy", now.Add(-20*24*time.Hour)),
+		finishedEvent("build", now.Add(-100*24*time.Hour)),
+		finishedEvent("build", now.Add(-95*24*time.Hour)),
+		finishedEvent("build", now.Add(-91*24*time.Hour)),
+		finishedEvent("build", now.Add(-10*24*time.Hour)),
+		{ID: primitive.NewObjectID(), Type: "build", State: model.EventStateStarted},
+	}}
+	archiver := &recordingArchiver{}
+	policy := Policy{Default: 90 * 24 * time.Hour, Types: map[string]time.Duration{"deploy": 30 * 24 * time.Hour}}
+	job := NewJob(store, archiver, nil, policy, 2, zap.NewNop())
+
+	previews, err := job.Preview(context.Background(), now)
+	require.NoError(t, err)
+	require.Len(t, previews, 2)
+	assert.Equal(t, "deploy", previews[0].Type)
+	assert.Equal(t, int64(1), previews[0].Count)
+	assert.Equal(t, "*", previews[1].Type)
+	assert.Equal(t, int64(3), previews[1].Count)
+	assert.Len(t, store.events, 7, "preview must not delete anything")
+
+	purged, err := job.Purge(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), purged)
+	assert.Len(t, store.events, 3)
+	require.Len(t, archiver.batches, 3, "batches hold at most two events")
+	assert.Len(t, archiver.batches[1], 2)
+
+	// A replaced policy applies to the next purge.
+	job.SetPolicy(Policy{Types: map[string]time.Duration{"build": 5 * 24 * time.Hour}})
+	purged, err = job.Purge(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Len(t, store.events, 2)
+}
+
+func TestFileArchiver(t *testing.T) {
+	dir := t.TempDir()
+	archiver, err := NewFileArchiver(dir)
+	require.NoError(t, err)
+
+	events := []model.Event{finishedEvent("deploy", time.Now()), finishedEvent("build", time.Now())}
+	require.NoError(t, archiver.Archive(context.Background(), events))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Regexp(t, `events-\d{8}T\d{6}Z-[0-9a-f]{24}\.ndjson\.gz$`, files[0])
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	var types []string
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var event model.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		types = append(types, event.Type)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"deploy", "build"}, types)
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{input: "90d", expected: 90 * 24 * time.Hour},
+		{input: "12h", expected: 12 * time.Hour},
+		{input: "0d", expectErr: true},
+		{input: "-1h", expectErr: true},
+		{input: "soon", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDuration(tt.input)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
+
+// fakeLock is held by another instance while held is set. Once taken, it
+// is lost as soon as the store deletes the first batch.
+type fakeLock struct {
+	held     bool
+	released bool
+	lose     context.CancelCauseFunc
+}
+
+func (l *fakeLock) Acquire(ctx context.Context) (context.Context, func() error, error) {
+	if l.held {
+		return nil, nil, ErrLocked
+	}
+	ctx, l.lose = context.WithCancelCause(ctx)
+	return ctx, func() error { l.released = true; return nil }, nil
+}
+
+// losingStore makes the lock lost once it deleted a batch.
+type losingStore struct {
+	*fakeStore
+	lock *fakeLock
+}
+
+func (s *losingStore) Delete(ctx context.Context, ids []primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.lock.lose(errors.New("lost the lease"))
+	return s.fakeStore.Delete(ctx, ids)
+}
+
+func TestJobLock(t *testing.T) {
+	now := time.Now()
+	newStore := func() *fakeStore {
+		return &fakeStore{events: []model.Event{
+			finishedEvent("deploy", now.Add(-40*24*time.Hour)),
+			finishedEvent("deploy", now.Add(-39*24*time.Hour)),
+			finishedEvent("deploy", now.Add(-38*24*time.Hour)),
+		}}
+	}
+	policy := Policy{Default: 30 * 24 * time.Hour}
+
+	t.Run("held by another instance", func(t *testing.T) {
+		store := newStore()
+		lock := &fakeLock{held: true}
+		NewJob(store, nil, lock, policy, 1, zap.NewNop()).runOnce(context.Background())
+		assert.Len(t, store.events, 3, "nothing is purged")
+	})
+
+	t.Run("released after purging", func(t *testing.T) {
+		store := newStore()
+		lock := &fakeLock{}
+		NewJob(store, nil, lock, policy, 1, zap.NewNop()).runOnce(context.Background())
+		assert.Empty(t, store.events)
+		assert.True(t, lock.released)
+	})
+
+	t.Run("lost while purging", func(t *testing.T) {
+		lock := &fakeLock{}
+		store := &losingStore{fakeStore: newStore(), lock: lock}
+		NewJob(store, nil, lock, policy, 1, zap.NewNop()).runOnce(context.Background())
+		assert.Len(t, store.events, 2, "the purge stops with the lock")
+		assert.True(t, lock.released)
+	})
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tracing"
+)
+
+var (
+	ErrInvalidAuditAction = errors.New("action must be one of start, finish or update")
+	ErrInvalidTimeRange   = errors.New("from must be before to")
+)
+
+type AuditService struct {
+	repo repository.IAuditRepository
+}
+
+func NewAuditService(repo repository.IAuditRepository) IAuditService {
+	return &AuditService{
+		repo: repo,
+	}
+}
+
+func (s *AuditService) ListAudit(ctx context.Context, filter model.AuditFilter) (_ []model.AuditEntry, err error) {
+	ctx, span := tracing.Start(ctx, "AuditService.ListAudit",
+		attribute.String("audit.actor", filter.Actor),
+		attribute.String("audit.action", string(filter.Action)),
+		attribute.Int64("offset", filter.Offset),
+		attribute.Int64("limit", filter.Limit))
+	defer func() { tracing.End(span, err) }()
+
+	switch filter.Action {
+	case "", model.AuditActionStart, model.AuditActionFinish, model.AuditActionUpdate:
+	default:
+		return nil, ErrInvalidAuditAction
+	}
+
+	if err := ValidateEventTypeFilter(filter.EventType); err != nil {
+		return nil, err
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	return s.repo.List(ctx, filter)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) List(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.AuditEntry), args.Error(1)
+}
+
+func TestAuditService_ListAudit(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		filter    model.AuditFilter
+		mockErr   error
+		expectErr error
+	}{
+		{
+			name:   "all filters",
+			filter: model.AuditFilter{Actor: "ci", Action: model.AuditActionFinish, EventType: "deploy", From: now.Add(-time.Hour), To: now, Limit: 10},
+		},
+		{
+			name:      "unknown action",
+			filter:    model.AuditFilter{Action: "delete", Limit: 10},
+			expectErr: ErrInvalidAuditAction,
+		},
+		{
+			name:      "invalid event type",
+			filter:    model.AuditFilter{EventType: "Deploy!", Limit: 10},
+			expectErr: ErrInvalidEventType,
+		},
+		{
+			name:      "empty time range",
+			filter:    model.AuditFilter{From: now, To: now, Limit: 10},
+			expectErr: ErrInvalidTimeRange,
+		},
+		{
+			name:      "repository error",
+			filter:    model.AuditFilter{Limit: 10},
+			mockErr:   errors.New("db error"),
+			expectErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockAuditRepository)
+			entries := []model.AuditEntry{{Actor: "ci", Action: model.AuditActionFinish}}
+			mockRepo.On("List", mock.Anything, tt.filter).Return(entries, tt.mockErr).Maybe()
+
+			svc := NewAuditService(mockRepo)
+			result, err := svc.ListAudit(context.Background(), tt.filter)
+
+			if tt.expectErr != nil {
+				assert.EqualError(t, err, tt.expectErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, entries, result)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
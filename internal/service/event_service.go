@@ -6,17 +6,39 @@ import (
 	"regexp"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
 	"github.com/godev/events-service/internal/repository"
+	"github.com/godev/events-service/internal/tracing"
 )
 
 var (
 	ErrInvalidEventType = errors.New("тип события может содержать только строчные буквы и цифры")
 	ErrInvalidLimit     = errors.New("limit не может быть больше 100")
 	ErrEventNotFound    = errors.New("no unfinished event found")
+	ErrNoSuchEvent      = errors.New("event not found")
+	ErrInvalidEventID   = errors.New("invalid event id")
+	ErrInvalidOperation = errors.New("operation must be either start or finish")
+	ErrEmptyBatch       = errors.New("batch must contain at least one operation")
+	ErrBatchTooLarge    = errors.New("batch cannot contain more than 100 operations")
+	ErrBatchAborted     = errors.New("operation rolled back because another operation in the batch failed")
 	eventTypeRegex      = regexp.MustCompile("^[a-z0-9]+$")
 )
 
+const maxBatchSize = 100
+
+// ValidateEventTypeFilter checks an optional event type used to filter
+// results; an empty type means "all types".
+func ValidateEventTypeFilter(eventType string) error {
+	if eventType != "" && !eventTypeRegex.MatchString(eventType) {
+		return ErrInvalidEventType
+	}
+	return nil
+}
+
 type EventService struct {
 	repo repository.IEventRepository
 }
@@ -27,7 +49,13 @@ func NewEventService(repo repository.IEventRepository) IEventService {
 	}
 }
 
-func (s *EventService) ListEvents(ctx context.Context, eventType string, offset, limit int64) ([]model.Event, error) {
+func (s *EventService) ListEvents(ctx context.Context, eventType string, offset, limit int64) (_ []model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.ListEvents",
+		attribute.String("event.type", eventType),
+		attribute.Int64("offset", offset),
+		attribute.Int64("limit", limit))
+	defer func() { tracing.End(span, err) }()
+
 	if limit > 100 {
 		return nil, ErrInvalidLimit
 	}
@@ -39,18 +67,89 @@ func (s *EventService) ListEvents(ctx context.Context, eventType string, offset,
 	return s.repo.List(ctx, eventType, offset, limit)
 }
 
+func (s *EventService) GetEvent(ctx context.Context, id string) (_ *model.Event, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEvent", attribute.String("event.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidEventID
+	}
+
+	event, err := s.repo.FindByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if event == nil {
+		return nil, ErrNoSuchEvent
+	}
+
+	return event, nil
+}
+
+func (s *EventService) GetEventHistory(ctx context.Context, id string) (_ []model.HistoryEntry, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEventHistory", attribute.String("event.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidEventID
+	}
+
+	event, err := s.repo.FindByID(ctx, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if event == nil {
+		return nil, ErrNoSuchEvent
+	}
+
+	return s.repo.History(ctx, objectID)
+}
+
+func (s *EventService) GetStats(ctx context.Context, eventType string) (_ []model.EventStats, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetStats", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
+	if err := ValidateEventTypeFilter(eventType); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Stats(ctx, eventType)
+}
+
+func (s *EventService) WatchEvents(ctx context.Context, eventType string, fn func(event model.Event) error) error {
+	if err := ValidateEventTypeFilter(eventType); err != nil {
+		return err
+	}
+
+	return s.repo.Watch(ctx, eventType, fn)
+}
+
 func (s *EventService) StartEvent(ctx context.Context, eventType string) error {
+	_, err := s.startEvent(ctx, eventType)
+	return err
+}
+
+// startEvent starts an event of eventType unless one is running already,
+// and tells whether it did.
+func (s *EventService) startEvent(ctx context.Context, eventType string) (started bool, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.StartEvent", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
 	if !eventTypeRegex.MatchString(eventType) {
-		return ErrInvalidEventType
+		return false, ErrInvalidEventType
 	}
 
-	existingEvent, err := s.repo.FindUnfinishedByType(ctx, eventType)
+	existingEvent, err := s.repo.FindUnfinishedByType(ctx, eventType, operation.Start)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	if existingEvent != nil {
-		return nil
+		return false, nil
 	}
 
 	event := &model.Event{
@@ -59,15 +158,24 @@ func (s *EventService) StartEvent(ctx context.Context, eventType string) error {
 		StartedAt: time.Now(),
 	}
 
-	return s.repo.Create(ctx, event)
+	// A concurrent request may have started the event since the lookup;
+	// starting is idempotent, so that is not an error.
+	err = s.repo.Create(ctx, event)
+	if errors.Is(err, repository.ErrUnfinishedExists) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
-func (s *EventService) FinishEvent(ctx context.Context, eventType string) error {
+func (s *EventService) FinishEvent(ctx context.Context, eventType string) (err error) {
+	ctx, span := tracing.Start(ctx, "EventService.FinishEvent", attribute.String("event.type", eventType))
+	defer func() { tracing.End(span, err) }()
+
 	if !eventTypeRegex.MatchString(eventType) {
 		return ErrInvalidEventType
 	}
 
-	event, err := s.repo.FindUnfinishedByType(ctx, eventType)
+	event, err := s.repo.FindUnfinishedByType(ctx, eventType, operation.Finish)
 	if err != nil {
 		return err
 	}
@@ -82,3 +190,80 @@ func (s *EventService) FinishEvent(ctx context.Context, eventType string) error
 
 	return s.repo.Update(ctx, event)
 }
+
+// ExecuteBatch runs ops in order and returns one error per operation, and
+// whether anything was written: starting an event that is already running
+// writes nothing. In atomic mode all operations share a single transaction:
+// the first failure rolls everything back and the remaining results are set
+// to ErrBatchAborted. The last return value is only set when the batch as a
+// whole could not run.
+func (s *EventService) ExecuteBatch(ctx context.Context, ops []model.BatchOperation, atomic bool) (_ []error, committed bool, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.ExecuteBatch",
+		attribute.Int("batch.size", len(ops)),
+		attribute.Bool("batch.atomic", atomic))
+	defer func() { tracing.End(span, err) }()
+
+	if len(ops) == 0 {
+		return nil, false, ErrEmptyBatch
+	}
+	if len(ops) > maxBatchSize {
+		return nil, false, ErrBatchTooLarge
+	}
+
+	results := make([]error, len(ops))
+	if !atomic {
+		for i, op := range ops {
+			var written bool
+			written, results[i] = s.executeOperation(ctx, op)
+			committed = committed || written
+		}
+		return results, committed, nil
+	}
+
+	failed := -1
+	err = s.repo.WithTransaction(ctx, func(txCtx context.Context) error {
+		// The callback may be retried on transient transaction errors.
+		failed = -1
+		committed = false
+		for i := range results {
+			results[i] = nil
+		}
+
+		for i, op := range ops {
+			written, err := s.executeOperation(txCtx, op)
+			if err != nil {
+				failed = i
+				results[i] = err
+				return err
+			}
+			committed = committed || written
+		}
+		return nil
+	})
+	if failed < 0 {
+		if err != nil {
+			return nil, false, err
+		}
+		return results, committed, nil
+	}
+
+	for i := range results {
+		if i != failed {
+			results[i] = ErrBatchAborted
+		}
+	}
+	return results, false, nil
+}
+
+// executeOperation runs op and tells whether it wrote anything.
+func (s *EventService) executeOperation(ctx context.Context, op model.BatchOperation) (bool, error) {
+	switch op.Op {
+	case model.BatchOperationStart:
+		return s.startEvent(ctx, op.Type)
+	case model.BatchOperationFinish:
+		err := s.FinishEvent(ctx, op.Type)
+		return err == nil, err
+	default:
+		return false, ErrInvalidOperation
+	}
+}
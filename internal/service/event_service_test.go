@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/operation"
+	"github.com/godev/events-service/internal/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -24,6 +26,14 @@ func (m *MockEventRepository) List(ctx context.Context, eventType string, offset
 	return args.Get(0).([]model.Event), args.Error(1)
 }
 
+func (m *MockEventRepository) Stats(ctx context.Context, eventType string) ([]model.EventStats, error) {
+	args := m.Called(ctx, eventType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.EventStats), args.Error(1)
+}
+
 func (m *MockEventRepository) Create(ctx context.Context, event *model.Event) error {
 	args := m.Called(ctx, event)
 	return args.Error(0)
@@ -34,7 +44,7 @@ func (m *MockEventRepository) Update(ctx context.Context, event *model.Event) er
 	return args.Error(0)
 }
 
-func (m *MockEventRepository) FindById(ctx context.Context, id primitive.ObjectID) (*model.Event, error) {
+func (m *MockEventRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Event, error) {
 	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -42,14 +52,42 @@ func (m *MockEventRepository) FindById(ctx context.Context, id primitive.ObjectI
 	return args.Get(0).(*model.Event), args.Error(1)
 }
 
-func (m *MockEventRepository) FindUnfinishedByType(ctx context.Context, eventType string) (*model.Event, error) {
-	args := m.Called(ctx, eventType)
+func (m *MockEventRepository) History(ctx context.Context, id primitive.ObjectID) ([]model.HistoryEntry, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.HistoryEntry), args.Error(1)
+}
+
+func (m *MockEventRepository) FindUnfinishedByType(ctx context.Context, eventType, op string) (*model.Event, error) {
+	args := m.Called(ctx, eventType, op)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*model.Event), args.Error(1)
 }
 
+func (m *MockEventRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
+func (m *MockEventRepository) Watch(ctx context.Context, eventType string, fn func(event model.Event) error) error {
+	args := m.Called(ctx, eventType)
+	if events, ok := args.Get(0).([]model.Event); ok {
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
 func TestEventService_ListEvents(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -139,6 +177,7 @@ func TestEventService_StartEvent(t *testing.T) {
 		eventType string
 		mockEvent *model.Event
 		mockErr   error
+		createErr error
 		expectErr error
 	}{
 		{
@@ -148,6 +187,12 @@ func TestEventService_StartEvent(t *testing.T) {
 			mockErr:   nil,
 			expectErr: nil,
 		},
+		{
+			name:      "started concurrently",
+			eventType: "test123",
+			createErr: repository.ErrUnfinishedExists,
+			expectErr: nil,
+		},
 		{
 			name:      "repository error",
 			eventType: "test123",
@@ -170,9 +215,9 @@ func TestEventService_StartEvent(t *testing.T) {
 			service := NewEventService(mockRepo)
 
 			if !errors.Is(tt.expectErr, ErrInvalidEventType) {
-				mockRepo.On("FindUnfinishedByType", mock.Anything, tt.eventType).Return(tt.mockEvent, tt.mockErr)
+				mockRepo.On("FindUnfinishedByType", mock.Anything, tt.eventType, operation.Start).Return(tt.mockEvent, tt.mockErr)
 				if tt.expectErr == nil {
-					mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+					mockRepo.On("Create", mock.Anything, mock.Anything).Return(tt.createErr)
 				}
 			}
 
@@ -231,7 +276,7 @@ func TestEventService_FinishEvent(t *testing.T) {
 			service := NewEventService(mockRepo)
 
 			if !errors.Is(tt.expectErr, ErrInvalidEventType) {
-				mockRepo.On("FindUnfinishedByType", mock.Anything, tt.eventType).Return(tt.mockEvent, tt.mockErr)
+				mockRepo.On("FindUnfinishedByType", mock.Anything, tt.eventType, operation.Finish).Return(tt.mockEvent, tt.mockErr)
 				if tt.mockEvent != nil {
 					mockRepo.On("Update", mock.Anything, tt.mockEvent).Return(nil)
 				}
@@ -249,3 +294,243 @@ func TestEventService_FinishEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestEventService_ExecuteBatch(t *testing.T) {
+	startedDeploy := &model.Event{
+		ID:      primitive.NewObjectID(),
+		Type:    "deploy",
+		State:   model.EventStateStarted,
+		Version: 1,
+	}
+
+	tests := []struct {
+		name            string
+		ops             []model.BatchOperation
+		atomic          bool
+		setup           func(m *MockEventRepository)
+		expectErrs      []error
+		expectCommitted bool
+		expectBatch     error
+	}{
+		{
+			name:        "empty batch",
+			ops:         nil,
+			setup:       func(m *MockEventRepository) {},
+			expectBatch: ErrEmptyBatch,
+		},
+		{
+			name:        "too many operations",
+			ops:         make([]model.BatchOperation, maxBatchSize+1),
+			setup:       func(m *MockEventRepository) {},
+			expectBatch: ErrBatchTooLarge,
+		},
+		{
+			name: "best effort keeps going after failure",
+			ops: []model.BatchOperation{
+				{Op: model.BatchOperationStart, Type: "build"},
+				{Op: model.BatchOperationFinish, Type: "missing"},
+				{Op: "cancel", Type: "build"},
+				{Op: model.BatchOperationFinish, Type: "deploy"},
+			},
+			setup: func(m *MockEventRepository) {
+				m.On("FindUnfinishedByType", mock.Anything, "build", operation.Start).Return(nil, nil)
+				m.On("Create", mock.Anything, mock.Anything).Return(nil)
+				m.On("FindUnfinishedByType", mock.Anything, "missing", operation.Finish).Return(nil, nil)
+				m.On("FindUnfinishedByType", mock.Anything, "deploy", operation.Finish).Return(startedDeploy, nil)
+				m.On("Update", mock.Anything, startedDeploy).Return(nil)
+			},
+			expectErrs:      []error{nil, ErrEventNotFound, ErrInvalidOperation, nil},
+			expectCommitted: true,
+		},
+		{
+			name: "starting running events writes nothing",
+			ops: []model.BatchOperation{
+				{Op: model.BatchOperationStart, Type: "deploy"},
+				{Op: model.BatchOperationStart, Type: "build"},
+			},
+			setup: func(m *MockEventRepository) {
+				m.On("FindUnfinishedByType", mock.Anything, "deploy", operation.Start).Return(startedDeploy, nil)
+				m.On("FindUnfinishedByType", mock.Anything, "build", operation.Start).Return(nil, nil)
+				m.On("Create", mock.Anything, mock.Anything).Return(repository.ErrUnfinishedExists)
+			},
+			expectErrs: []error{nil, nil},
+		},
+		{
+			name:   "atomic commits when every operation succeeds",
+			atomic: true,
+			ops: []model.BatchOperation{
+				{Op: model.BatchOperationStart, Type: "build"},
+				{Op: model.BatchOperationFinish, Type: "deploy"},
+			},
+			setup: func(m *MockEventRepository) {
+				m.On("WithTransaction", mock.Anything).Return(nil)
+				m.On("FindUnfinishedByType", mock.Anything, "build", operation.Start).Return(nil, nil)
+				m.On("Create", mock.Anything, mock.Anything).Return(nil)
+				m.On("FindUnfinishedByType", mock.Anything, "deploy", operation.Finish).Return(startedDeploy, nil)
+				m.On("Update", mock.Anything, startedDeploy).Return(nil)
+			},
+			expectErrs:      []error{nil, nil},
+			expectCommitted: true,
+		},
+		{
+			name:   "atomic aborts the remaining operations",
+			atomic: true,
+			ops: []model.BatchOperation{
+				{Op: model.BatchOperationStart, Type: "build"},
+				{Op: model.BatchOperationFinish, Type: "missing"},
+				{Op: model.BatchOperationStart, Type: "test"},
+			},
+			setup: func(m *MockEventRepository) {
+				m.On("WithTransaction", mock.Anything).Return(nil)
+				m.On("FindUnfinishedByType", mock.Anything, "build", operation.Start).Return(nil, nil)
+				m.On("Create", mock.Anything, mock.Anything).Return(nil)
+				m.On("FindUnfinishedByType", mock.Anything, "missing", operation.Finish).Return(nil, nil)
+			},
+			expectErrs: []error{ErrBatchAborted, ErrEventNotFound, ErrBatchAborted},
+		},
+		{
+			name:   "atomic transaction failure",
+			atomic: true,
+			ops: []model.BatchOperation{
+				{Op: model.BatchOperationStart, Type: "build"},
+			},
+			setup: func(m *MockEventRepository) {
+				m.On("WithTransaction", mock.Anything).Return(errors.New("transaction error"))
+			},
+			expectBatch: errors.New("transaction error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockEventRepository)
+			tt.setup(mockRepo)
+			service := NewEventService(mockRepo)
+
+			errs, committed, err := service.ExecuteBatch(context.Background(), tt.ops, tt.atomic)
+			if tt.expectBatch != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectBatch.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectErrs, errs)
+				assert.Equal(t, tt.expectCommitted, committed)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEventService_GetEvent(t *testing.T) {
+	id := primitive.NewObjectID()
+	event := &model.Event{ID: id, Type: "test123", State: model.EventStateStarted, Version: 1}
+
+	tests := []struct {
+		name      string
+		id        string
+		mockEvent *model.Event
+		mockErr   error
+		expectErr error
+	}{
+		{
+			name:      "successful get",
+			id:        id.Hex(),
+			mockEvent: event,
+		},
+		{
+			name:      "event not found",
+			id:        id.Hex(),
+			expectErr: ErrNoSuchEvent,
+		},
+		{
+			name:      "repository error",
+			id:        id.Hex(),
+			mockErr:   errors.New("repository error"),
+			expectErr: errors.New("repository error"),
+		},
+		{
+			name:      "invalid id",
+			id:        "not-an-id",
+			expectErr: ErrInvalidEventID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockEventRepository)
+			service := NewEventService(mockRepo)
+
+			if !errors.Is(tt.expectErr, ErrInvalidEventID) {
+				mockRepo.On("FindByID", mock.Anything, id).Return(tt.mockEvent, tt.mockErr)
+			}
+
+			got, err := service.GetEvent(context.Background(), tt.id)
+			if tt.expectErr != nil {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectErr.Error(), err.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.mockEvent, got)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEventService_GetEventHistory(t *testing.T) {
+	id := primitive.NewObjectID()
+	event := &model.Event{ID: id, Type: "test123", State: model.EventStateFinished, Version: 2}
+	history := []model.HistoryEntry{
+		{EventID: id, Transition: model.TransitionStarted},
+		{EventID: id, Transition: model.TransitionFinished},
+	}
+
+	tests := []struct {
+		name      string
+		id        string
+		mockEvent *model.Event
+		expectErr error
+	}{
+		{
+			name:      "successful get",
+			id:        id.Hex(),
+			mockEvent: event,
+		},
+		{
+			name:      "event not found",
+			id:        id.Hex(),
+			expectErr: ErrNoSuchEvent,
+		},
+		{
+			name:      "invalid id",
+			id:        "not-an-id",
+			expectErr: ErrInvalidEventID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockEventRepository)
+			service := NewEventService(mockRepo)
+
+			if !errors.Is(tt.expectErr, ErrInvalidEventID) {
+				mockRepo.On("FindByID", mock.Anything, id).Return(tt.mockEvent, nil)
+			}
+			if tt.expectErr == nil {
+				mockRepo.On("History", mock.Anything, id).Return(history, nil)
+			}
+
+			got, err := service.GetEventHistory(context.Background(), tt.id)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, history, got)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -8,6 +8,15 @@ import (
 
 type IEventService interface {
 	ListEvents(ctx context.Context, eventType string, offset, limit int64) ([]model.Event, error)
+	GetEvent(ctx context.Context, id string) (*model.Event, error)
+	GetEventHistory(ctx context.Context, id string) ([]model.HistoryEntry, error)
+	GetStats(ctx context.Context, eventType string) ([]model.EventStats, error)
 	StartEvent(ctx context.Context, eventType string) error
 	FinishEvent(ctx context.Context, eventType string) error
+	ExecuteBatch(ctx context.Context, ops []model.BatchOperation, atomic bool) ([]error, bool, error)
+	WatchEvents(ctx context.Context, eventType string, fn func(event model.Event) error) error
+}
+
+type IAuditService interface {
+	ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
 }
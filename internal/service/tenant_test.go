@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/config"
+	"github.com/godev/events-service/internal/db"
+	"github.com/godev/events-service/internal/model"
+	"github.com/godev/events-service/internal/repository/sqlite"
+	"github.com/godev/events-service/internal/tenant"
+)
+
+// newSQLiteService runs the service on a SQLite file, the one backend that
+// needs no server, so that tenant scoping is checked end to end.
+func newSQLiteService(t *testing.T) IEventService {
+	database, err := db.NewSQLite(zap.NewNop(), &config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "events.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	repo, err := sqlite.NewEventRepository(database.GetDB())
+	require.NoError(t, err)
+	return NewEventService(repo)
+}
+
+func TestEventServiceTenantIsolation(t *testing.T) {
+	svc := newSQLiteService(t)
+	ctxA := tenant.WithTenant(context.Background(), "team-a")
+	ctxB := tenant.WithTenant(context.Background(), "team-b")
+
+	require.NoError(t, svc.StartEvent(ctxA, "deploy"))
+	require.NoError(t, svc.StartEvent(ctxB, "deploy"), "the same type runs in both tenants")
+
+	eventsA, err := svc.ListEvents(ctxA, "", 0, 100)
+	require.NoError(t, err)
+	require.Len(t, eventsA, 1)
+	assert.Equal(t, "team-a", eventsA[0].Tenant)
+
+	_, err = svc.GetEvent(ctxB, eventsA[0].ID.Hex())
+	assert.ErrorIs(t, err, ErrNoSuchEvent, "team-b cannot read team-a's event")
+	_, err = svc.GetEventHistory(ctxB, eventsA[0].ID.Hex())
+	assert.ErrorIs(t, err, ErrNoSuchEvent)
+
+	require.NoError(t, svc.FinishEvent(ctxA, "deploy"))
+	assert.ErrorIs(t, svc.FinishEvent(ctxA, "deploy"), ErrEventNotFound)
+
+	eventsB, err := svc.ListEvents(ctxB, "deploy", 0, 100)
+	require.NoError(t, err)
+	require.Len(t, eventsB, 1)
+	assert.Equal(t, model.EventStateStarted, eventsB[0].State, "finishing in team-a leaves team-b's event running")
+
+	errs, committed, err := svc.ExecuteBatch(ctxB, []model.BatchOperation{
+		{Op: model.BatchOperationFinish, Type: "deploy"},
+		{Op: model.BatchOperationFinish, Type: "deploy"},
+	}, false)
+	require.NoError(t, err)
+	assert.True(t, committed)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], ErrEventNotFound)
+
+	statsA, err := svc.GetStats(ctxA, "")
+	require.NoError(t, err)
+	require.Len(t, statsA, 1)
+	assert.Equal(t, "team-a", statsA[0].Tenant)
+	assert.Equal(t, int64(1), statsA[0].Finished)
+}
@@ -0,0 +1,55 @@
+// Package tenant carries the namespace a request operates in. Events of
+// different tenants are fully isolated from each other.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// Default is the tenant of requests that do not name one, and of events
+// created before tenants were introduced.
+const Default = "default"
+
+// Header lets callers that are not bound to a tenant choose one.
+const Header = "X-Tenant-ID"
+
+var (
+	ErrInvalid = errors.New("tenant may contain lowercase letters, digits, '-' and '_' and must be at most 63 characters")
+	nameRegex  = regexp.MustCompile("^[a-z0-9][a-z0-9_-]{0,62}$")
+)
+
+func Validate(name string) error {
+	if !nameRegex.MatchString(name) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+type allTenantsKey struct{}
+
+func WithTenant(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, name)
+}
+
+// FromContext returns the tenant of ctx, or Default when none was set.
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(tenantKey{}).(string); ok && name != "" {
+		return name
+	}
+	return Default
+}
+
+// WithAllTenants lifts tenant scoping for background jobs such as metrics
+// collection. It must never be derived from a caller's request.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey{}, true)
+}
+
+func All(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsKey{}).(bool)
+	return all
+}
@@ -0,0 +1,161 @@
+// Package tlsconfig builds the TLS configuration of the servers from
+// certificate files and picks up new certificates without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/godev/events-service/internal/config"
+)
+
+// checkInterval is how often handshakes look at the files for changes.
+var checkInterval = 10 * time.Second
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// New returns the server configuration for cfg. The certificate, key and
+// client CA bundle are loaded now and again after any of their files
+// changes; a change that fails to load is logged and the previous files
+// stay in use.
+func New(cfg *config.TLSConfig, log *zap.Logger) (*tls.Config, error) {
+	minVersion, ok := versions[cfg.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS version %q", cfg.MinVersion)
+	}
+
+	files := &files{cfg: cfg, log: log}
+	if err := files.load(); err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{MinVersion: minVersion}
+	if cfg.ClientCAFile != "" {
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+		if cfg.ClientAuth == "optional" {
+			base.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	server := base.Clone()
+	server.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cert, clientCAs := files.current()
+		c := base.Clone()
+		c.Certificates = []tls.Certificate{*cert}
+		c.ClientCAs = clientCAs
+		return c, nil
+	}
+	return server, nil
+}
+
+// files holds what was last loaded from the configured files.
+type files struct {
+	cfg *config.TLSConfig
+	log *zap.Logger
+
+	mu        sync.Mutex
+	checked   time.Time
+	versions  []fileVersion
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// fileVersion tells whether a file changed since it was loaded.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func (f *files) current() (*tls.Certificate, *x509.CertPool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.checked) >= checkInterval {
+		f.checked = time.Now()
+		if versions, err := f.stat(); err == nil && !equalVersions(versions, f.versions) {
+			if err := f.loadLocked(); err != nil {
+				f.log.Error("Failed to reload TLS certificates, keeping the previous ones", zap.Error(err))
+				// Do not retry until the files change again.
+				f.versions = versions
+			} else {
+				f.log.Info("Reloaded TLS certificates", zap.String("cert_file", f.cfg.CertFile))
+			}
+		}
+	}
+	return f.cert, f.clientCAs
+}
+
+func (f *files) load() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checked = time.Now()
+	return f.loadLocked()
+}
+
+func (f *files) loadLocked() error {
+	// Stat before reading, so that a write racing with the read shows up as
+	// a change on the next check.
+	versions, err := f.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.cfg.CertFile, f.cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if f.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(f.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in " + f.cfg.ClientCAFile)
+		}
+	}
+
+	f.versions, f.cert, f.clientCAs = versions, &cert, clientCAs
+	return nil
+}
+
+func (f *files) stat() ([]fileVersion, error) {
+	names := []string{f.cfg.CertFile, f.cfg.KeyFile}
+	if f.cfg.ClientCAFile != "" {
+		names = append(names, f.cfg.ClientCAFile)
+	}
+
+	versions := make([]fileVersion, len(names))
+	for i, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		versions[i] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+	}
+	return versions, nil
+}
+
+func equalVersions(a, b []fileVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,4 @@
+// Package eventsv1 contains the generated gRPC API of the events service.
+package eventsv1
+
+//go:generate protoc -I ../../../../api/proto --go_out=../../../.. --go_opt=module=github.com/godev/events-service --go-grpc_out=../../../.. --go-grpc_opt=module=github.com/godev/events-service events/v1/events.proto
@@ -0,0 +1,276 @@
+// Package client is a Go client for the events service REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	requestIDHeader      = "X-Request-ID"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retry      RetryPolicy
+	headers    http.Header
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithHeader adds a header to every request, e.g. for authentication.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(key, value)
+	}
+}
+
+// WithAPIKey authenticates every request with an API key.
+func WithAPIKey(key string) Option {
+	return WithHeader("X-API-Key", key)
+}
+
+// WithBearerToken authenticates every request with a JWT.
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithTenant makes every request operate in tenant. Keys bound to a tenant
+// do not need it.
+func WithTenant(tenant string) Option {
+	return WithHeader("X-Tenant-ID", tenant)
+}
+
+// New creates a client for the service at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("events client: base URL must be absolute")
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+		headers:    make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey makes the next mutating call use key instead of a
+// freshly generated one, so that retries across process restarts are
+// recognised by the server as well.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+func idempotencyKey(ctx context.Context) string {
+	if key, ok := ctx.Value(idempotencyKeyCtx{}).(string); ok && key != "" {
+		return key
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	// idempotent requests carry an Idempotency-Key that stays the same
+	// across retries.
+	idempotent bool
+}
+
+// do sends req, retrying transport errors, 409, 429 and 5xx responses with
+// exponential backoff, and decodes a successful JSON body into out.
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return err
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	var key string
+	if req.idempotent {
+		key = idempotencyKey(ctx)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return err
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		for k, v := range c.headers {
+			httpReq.Header[k] = v
+		}
+		if body != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		if key != "" {
+			httpReq.Header.Set(idempotencyKeyHeader, key)
+		}
+
+		var retry bool
+		retry, lastErr = c.send(httpReq, out)
+		if lastErr == nil || !retry {
+			break
+		}
+	}
+
+	var rerr *retryableError
+	if errors.As(lastErr, &rerr) {
+		return rerr.APIError
+	}
+	return lastErr
+}
+
+func (c *Client) send(req *http.Request, out interface{}) (bool, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return req.Context().Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if out == nil || len(data) == 0 {
+			return false, nil
+		}
+		return false, json.Unmarshal(data, out)
+	}
+
+	apiErr := &retryableError{
+		APIError: &APIError{
+			StatusCode: resp.StatusCode,
+			RequestID:  resp.Header.Get(requestIDHeader),
+		},
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	var errResp model.ErrorResponse
+	if json.Unmarshal(data, &errResp) == nil {
+		apiErr.Message = errResp.Message
+	}
+
+	retry := resp.StatusCode == http.StatusConflict ||
+		resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= http.StatusInternalServerError
+	return retry, apiErr
+}
+
+// retryableError keeps the server's Retry-After hint next to the APIError
+// without exposing it to callers.
+type retryableError struct {
+	*APIError
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.APIError
+}
+
+func (c *Client) backoff(attempt int, lastErr error) time.Duration {
+	var rerr *retryableError
+	if errors.As(lastErr, &rerr) && rerr.retryAfter > 0 {
+		return rerr.retryAfter
+	}
+
+	d := float64(c.retry.MinBackoff) * math.Pow(2, float64(attempt-1))
+	if d > float64(c.retry.MaxBackoff) {
+		d = float64(c.retry.MaxBackoff)
+	}
+	// Equal jitter: wait between half and the whole backoff.
+	return time.Duration(d/2 + mathrand.Float64()*d/2)
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, WithRetryPolicy(fastRetry))
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_StartRetriesWithSameIdempotencyKey(t *testing.T) {
+	var keys []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	require.NoError(t, c.Start(context.Background(), "deploy"))
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+}
+
+func TestClient_FinishDoesNotRetryNotFound(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(model.ErrorResponse{Message: "no unfinished event found"})
+	})
+
+	err := c.Finish(context.Background(), "deploy")
+	require.Error(t, err)
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, 1, calls)
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "no unfinished event found", apiErr.Message)
+}
+
+func TestClient_GivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	err := c.Start(context.Background(), "deploy")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, fastRetry.MaxAttempts, calls)
+}
+
+func TestClient_RetriesRateLimited(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	err := c.Start(context.Background(), "deploy")
+	assert.True(t, IsRateLimited(err))
+	assert.Equal(t, fastRetry.MaxAttempts, calls)
+}
+
+func TestClient_EventsPaginates(t *testing.T) {
+	const total = 7
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		assert.Equal(t, "deploy", r.URL.Query().Get("type"))
+
+		var resp model.EventsResponse
+		for i := offset; i < total && i < offset+limit; i++ {
+			resp.Events = append(resp.Events, model.Event{Type: "deploy", Version: int64(i)})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	var versions []int64
+	for event, err := range c.Events(context.Background(), ListOptions{Type: "deploy", Limit: 3}) {
+		require.NoError(t, err)
+		versions = append(versions, event.Version)
+	}
+	assert.Equal(t, []int64{0, 1, 2, 3, 4, 5, 6}, versions)
+}
+
+func TestClient_TrackFinishesOnPanic(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = c.Track(context.Background(), "deploy", func(ctx context.Context) error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, []string{"/v1/start", "/v1/finish"}, paths)
+
+	paths = nil
+	workErr := errors.New("work failed")
+	err := c.Track(context.Background(), "deploy", func(ctx context.Context) error {
+		return workErr
+	})
+	assert.ErrorIs(t, err, workErr)
+	assert.Equal(t, []string{"/v1/start", "/v1/finish"}, paths)
+}
+
+func TestClient_Watch(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/watch", r.URL.Path)
+		assert.Equal(t, "deploy", r.URL.Query().Get("type"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event:event\ndata:{\"type\":\"deploy\",\"state\":0,\"version\":1}\n\n"))
+		_, _ = w.Write([]byte("event: event\ndata: {\"type\":\"deploy\",\"state\":1,\"version\":2}\n\n"))
+	})
+
+	var received []Event
+	err := c.Watch(context.Background(), "deploy", func(event Event) error {
+		received = append(received, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, received, 2)
+	assert.Equal(t, EventStateStarted, received[0].State)
+	assert.Equal(t, EventStateFinished, received[1].State)
+	assert.Equal(t, int64(2), received[1].Version)
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+const maxPageSize = 100
+
+func (c *Client) Start(ctx context.Context, eventType string) error {
+	return c.do(ctx, request{
+		method:     http.MethodPost,
+		path:       "/v1/start",
+		body:       model.EventRequest{Type: eventType},
+		idempotent: true,
+	}, nil)
+}
+
+// Finish finishes the running event of eventType. It returns an error for
+// which IsNotFound reports true when no such event is running.
+func (c *Client) Finish(ctx context.Context, eventType string) error {
+	return c.do(ctx, request{
+		method:     http.MethodPost,
+		path:       "/v1/finish",
+		body:       model.EventRequest{Type: eventType},
+		idempotent: true,
+	}, nil)
+}
+
+func (c *Client) Get(ctx context.Context, id string) (*Event, error) {
+	var event Event
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/v1/events/" + url.PathEscape(id),
+	}, &event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// List returns a single page of events, newest first.
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]Event, error) {
+	query := url.Values{}
+	if opts.Type != "" {
+		query.Set("type", opts.Type)
+	}
+	query.Set("offset", strconv.FormatInt(opts.Offset, 10))
+	query.Set("limit", strconv.FormatInt(pageSize(opts.Limit), 10))
+
+	var resp model.EventsResponse
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/v1",
+		query:  query,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// Events iterates over all events matching opts, fetching pages of
+// opts.Limit events as needed. Iteration stops at the first error.
+func (c *Client) Events(ctx context.Context, opts ListOptions) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		opts.Limit = pageSize(opts.Limit)
+		for {
+			events, err := c.List(ctx, opts)
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+
+			for _, event := range events {
+				if !yield(event, nil) {
+					return
+				}
+			}
+
+			if int64(len(events)) < opts.Limit {
+				return
+			}
+			opts.Offset += int64(len(events))
+		}
+	}
+}
+
+// Batch runs ops in a single request. With atomic set either all of them
+// are applied or none; check BatchResponse.Committed and the per-operation
+// statuses.
+func (c *Client) Batch(ctx context.Context, ops []BatchOperation, atomic bool) (*BatchResponse, error) {
+	var resp BatchResponse
+	err := c.do(ctx, request{
+		method:     http.MethodPost,
+		path:       "/v1/batch",
+		body:       model.BatchRequest{Atomic: atomic, Operations: ops},
+		idempotent: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Track starts an event of eventType, runs fn and finishes the event
+// afterwards, even when fn fails or panics. A panic is re-raised once the
+// event is finished.
+func (c *Client) Track(ctx context.Context, eventType string, fn func(ctx context.Context) error) (err error) {
+	if err := c.Start(ctx, eventType); err != nil {
+		return fmt.Errorf("start %s: %w", eventType, err)
+	}
+
+	defer func() {
+		// Finish even when ctx was cancelled by the work itself.
+		finishErr := c.Finish(context.WithoutCancel(ctx), eventType)
+		if r := recover(); r != nil {
+			panic(r)
+		}
+		if err == nil && finishErr != nil {
+			err = fmt.Errorf("finish %s: %w", eventType, finishErr)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func pageSize(limit int64) int64 {
+	if limit <= 0 || limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+// Stats returns per-type counters, for all types when eventType is empty.
+func (c *Client) Stats(ctx context.Context, eventType string) ([]EventStats, error) {
+	query := url.Values{}
+	if eventType != "" {
+		query.Set("type", eventType)
+	}
+
+	var resp model.StatsResponse
+	err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/v1/stats",
+		query:  query,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Stats, nil
+}
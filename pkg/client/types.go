@@ -0,0 +1,31 @@
+package client
+
+import "github.com/godev/events-service/internal/model"
+
+// The client speaks the same JSON as the server, so the wire types are
+// shared with the service instead of being duplicated here.
+type (
+	Event              = model.Event
+	EventState         = model.EventState
+	BatchOperation     = model.BatchOperation
+	BatchOperationType = model.BatchOperationType
+	BatchResult        = model.BatchResult
+	BatchResponse      = model.BatchResponse
+	EventStats         = model.EventStats
+)
+
+const (
+	EventStateStarted  = model.EventStateStarted
+	EventStateFinished = model.EventStateFinished
+
+	BatchOperationStart  = model.BatchOperationStart
+	BatchOperationFinish = model.BatchOperationFinish
+)
+
+type ListOptions struct {
+	// Type limits the result to events of a single type.
+	Type   string
+	Offset int64
+	// Limit is the page size, at most 100. Zero means 100.
+	Limit int64
+}
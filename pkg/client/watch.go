@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/godev/events-service/internal/model"
+)
+
+// Watch calls fn for every event of eventType (all types when empty) that
+// is started or finished until ctx is cancelled, fn returns an error or the
+// server ends the stream, in which case it returns nil. Watch does not
+// retry; callers that want to keep tailing should call it again.
+func (c *Client) Watch(ctx context.Context, eventType string, fn func(event Event) error) error {
+	u := *c.baseURL
+	u.Path += "/v1/watch"
+	if eventType != "" {
+		u.RawQuery = url.Values{"type": {eventType}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.headers {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errResp model.ErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil {
+			apiErr.Message = errResp.Message
+		}
+		return apiErr
+	}
+
+	err = readEvents(resp.Body, func(name string, data []byte) error {
+		switch name {
+		case "event":
+			var event Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				return err
+			}
+			return fn(event)
+		case "error":
+			var errResp model.ErrorResponse
+			_ = json.Unmarshal(data, &errResp)
+			return &APIError{StatusCode: http.StatusInternalServerError, Message: errResp.Message}
+		default:
+			return nil
+		}
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// readEvents parses a text/event-stream body and calls fn per message.
+func readEvents(r io.Reader, fn func(name string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var name string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if err := fn(name, []byte(strings.Join(data, "\n"))); err != nil {
+					return err
+				}
+			}
+			name, data = "", nil
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	return scanner.Err()
+}